@@ -0,0 +1,36 @@
+package goiforest
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func BenchmarkBuildForest(b *testing.B) {
+	ds := testDataSet(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 100, SampleSize: 256, Seed: 1, Workers: 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSample(b *testing.B) {
+	ds := testDataSet(10000)
+	r := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ds.SampleRand(256, r)
+	}
+}
+
+func BenchmarkSplit(b *testing.B) {
+	ds := testDataSet(10000)
+	r := rand.New(rand.NewSource(1))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := ds.SplitRand(nil, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
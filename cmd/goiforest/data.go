@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mikemherron/goiforest"
+)
+
+// dataFlags are the flags shared by commands that read a data set from CSV.
+type dataFlags struct {
+	in         string
+	schema     string
+	schemaFile string
+	saveSchema string
+	exclude    string
+	csvFlags
+}
+
+func (d *dataFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&d.in, "in", "", "input CSV `file` with a header row")
+	fs.StringVar(&d.schema, "schema", "",
+		"comma separated `name:type` pairs, where type is numerical or categorical, "+
+			"overriding the inferred type of those columns")
+	fs.StringVar(&d.schemaFile, "schema-file", "", "JSON schema `file` to use rather than inferring one")
+	fs.StringVar(&d.saveSchema, "save-schema", "", "save the schema used as JSON to `file`")
+	fs.StringVar(&d.exclude, "exclude", "", "comma separated `columns` to ignore, such as labels")
+	d.csvFlags.register(fs)
+}
+
+// csvFlags are the flags controlling how values are read from CSV files.
+type csvFlags struct {
+	missing string
+	onError string
+}
+
+func (c *csvFlags) register(fs *flag.FlagSet) {
+	var tokens []string
+	for _, token := range goiforest.DefaultMissingTokens {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	fs.StringVar(&c.missing, "missing", strings.Join(tokens, ","),
+		"comma separated `tokens` read as missing values, in addition to empty values")
+	fs.StringVar(&c.onError, "on-error", "fail",
+		"how to handle values that cannot be read: fail, skip the row, or read as missing")
+}
+
+// options returns the CSV options given by the flags, with a report to be
+// printed by printReport.
+func (c *csvFlags) options() (goiforest.CSVOptions, error) {
+	opts := goiforest.CSVOptions{
+		MissingTokens: missingTokens(c.missing),
+		Report:        &goiforest.CSVReport{},
+	}
+	switch c.onError {
+	case "fail":
+		opts.OnError = goiforest.CSVErrorFail
+	case "skip":
+		opts.OnError = goiforest.CSVErrorSkipRow
+	case "missing":
+		opts.OnError = goiforest.CSVErrorMissing
+	default:
+		return opts, fmt.Errorf("unknown -on-error policy %q, expected fail, skip or missing", c.onError)
+	}
+	return opts, nil
+}
+
+// printReport prints the report of reading path if any errors were handled.
+func printReport(w io.Writer, path string, report *goiforest.CSVReport) {
+	if report.SkippedRows > 0 || report.MissingValues > 0 {
+		fmt.Fprintf(w, "%s: %v\n", path, report)
+	}
+}
+
+// missingTokens returns the tokens listed by the missing flag plus the empty
+// value.
+func missingTokens(list string) []string {
+	return append([]string{""}, splitList(list)...)
+}
+
+// load reads the data set named by the flags, printing a summary of any
+// errors handled to stderr.
+func (d *dataFlags) load(stderr io.Writer) (*goiforest.DataSet, error) {
+	csvOpts, err := d.options()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(d.in)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	override, err := parseSchema(d.schema)
+	if err != nil {
+		return nil, err
+	}
+	opts := goiforest.InferOptions{
+		CSVOptions: csvOpts,
+		Override:   override,
+		Exclude:    splitList(d.exclude),
+	}
+
+	var dataSet *goiforest.DataSet
+	var schema goiforest.Schema
+	if d.schemaFile != "" {
+		schema, err = loadSchema(d.schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		for name, typ := range override {
+			schema[name] = typ
+		}
+		for _, name := range opts.Exclude {
+			delete(schema, name)
+		}
+		dataSet, err = goiforest.NewDataSetFromCSVWithOptions(csv.NewReader(file), schema, opts.CSVOptions)
+	} else {
+		dataSet, schema, err = goiforest.NewDataSetFromCSVInferred(csv.NewReader(file), opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", d.in, err)
+	}
+	if len(dataSet.Attributes) == 0 {
+		return nil, fmt.Errorf("no attributes to read from %s", d.in)
+	}
+	printReport(stderr, d.in, csvOpts.Report)
+
+	if d.saveSchema != "" {
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(d.saveSchema, append(data, '\n'), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return dataSet, nil
+}
+
+// parseSchema parses a list of name:type pairs.
+func parseSchema(schema string) (goiforest.Schema, error) {
+	attributes := goiforest.Schema{}
+	for _, pair := range splitList(schema) {
+		name, typeName, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("schema entry %q is not name:type", pair)
+		}
+		typ, err := goiforest.ParseAttributeType(strings.TrimSpace(typeName))
+		if err != nil {
+			return nil, fmt.Errorf("schema entry %q: %w", pair, err)
+		}
+		attributes[strings.TrimSpace(name)] = typ
+	}
+	return attributes, nil
+}
+
+func loadSchema(path string) (goiforest.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema goiforest.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("error reading schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
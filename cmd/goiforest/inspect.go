@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mikemherron/goiforest"
+)
+
+func runInspect(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("inspect", "-model model.bin [flags]")
+	model := fs.String("model", "", "model `file` saved by train")
+	trees := fs.Bool("trees", false, "print every tree")
+	tree := fs.Int("tree", -1, "print only the tree at `index`")
+	reference := fs.String("reference", "",
+		"CSV `file` used to compute how much each attribute separates anomalies from inliers")
+	var csvFlags csvFlags
+	csvFlags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "model"); err != nil {
+		return err
+	}
+
+	forest, err := loadModel(*model)
+	if err != nil {
+		return err
+	}
+
+	if *tree >= 0 {
+		if *tree >= len(forest.Trees) {
+			return fmt.Errorf("tree %d out of range, the forest has %d trees", *tree, len(forest.Trees))
+		}
+		fmt.Fprint(stdout, forest.Trees[*tree])
+		return nil
+	}
+
+	var referenceData *goiforest.DataSet
+	if *reference != "" {
+		csvOpts, err := csvFlags.options()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(*reference)
+		if err != nil {
+			return err
+		}
+		referenceData, err = forestDataSet(forest, data, csvOpts)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", *reference, err)
+		}
+		printReport(stderr, *reference, csvOpts.Report)
+	}
+	importances, err := forest.FeatureImportance(referenceData)
+	if err != nil {
+		return err
+	}
+
+	config := forest.Config()
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Trees:\t%d\n", len(forest.Trees))
+	fmt.Fprintf(tw, "Sample size:\t%d\n", config.SampleSize)
+	fmt.Fprintf(tw, "Max depth:\t%d\n", config.MaxDepth)
+	fmt.Fprintf(tw, "Seed:\t%d\n", config.Seed)
+	if config.Extended {
+		fmt.Fprintf(tw, "Extension level:\t%d\n", config.ExtensionLevel)
+	} else {
+		fmt.Fprintf(tw, "Split strategy:\t%s\n", strategyName(config.SplitStrategy))
+	}
+	fmt.Fprintf(tw, "Unseen categories:\t%v\n", config.UnseenCategories)
+	if config.Contamination > 0 {
+		fmt.Fprintf(tw, "Contamination:\t%g\n", config.Contamination)
+	}
+	fmt.Fprintf(tw, "Threshold:\t%f\n", forest.Threshold())
+	fmt.Fprintln(tw)
+
+	fmt.Fprint(tw, "Attribute\tType\tSplits\tFrequency\tAverage depth")
+	if referenceData != nil {
+		fmt.Fprint(tw, "\tImportance")
+	}
+	fmt.Fprintln(tw)
+	for _, imp := range importances {
+		fmt.Fprintf(tw, "%s\t%v\t%d\t%.4f\t%.4f", imp.Attribute.Name, imp.Attribute.Type,
+			imp.Splits, imp.Frequency, imp.AverageDepth)
+		if referenceData != nil {
+			fmt.Fprintf(tw, "\t%.4f", imp.Importance)
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if *trees {
+		for i, t := range forest.Trees {
+			fmt.Fprintf(stdout, "\nTree %d\n%s", i, t)
+		}
+	}
+	return nil
+}
+
+// forestDataSet reads the forest's attributes from CSV data.
+func forestDataSet(forest *goiforest.IsolationForest, data []byte, opts goiforest.CSVOptions) (*goiforest.DataSet, error) {
+	attributes := map[string]goiforest.AttributeType{}
+	for _, attr := range forest.Attributes() {
+		attributes[attr.Name] = attr.Type
+	}
+	return goiforest.NewDataSetFromCSVWithOptions(csv.NewReader(bytes.NewReader(data)), attributes, opts)
+}
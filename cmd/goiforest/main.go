@@ -0,0 +1,99 @@
+// Command goiforest trains isolation forests on CSV files and uses them to
+// score, inspect and describe data, and to serve scores over HTTP.
+//
+// Usage:
+//
+//	goiforest train -in data.csv -out model.bin [flags]
+//	goiforest score -model model.bin -in data.csv [-out scored.csv] [flags]
+//	goiforest inspect -model model.bin [flags]
+//	goiforest stats -in data.csv [flags]
+//	goiforest serve -model model.bin [-addr :8080] [flags]
+//
+// Run goiforest <command> -h for the flags of each command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"train", "build a forest from a CSV file and save it", runTrain},
+	{"score", "score the rows of a CSV file with a saved forest", runScore},
+	{"inspect", "describe a saved forest", runInspect},
+	{"stats", "print statistics of the attributes in a CSV file", runStats},
+	{"serve", "serve scores from a saved forest over HTTP", runServe},
+}
+
+// errUsage is returned by run when no command is given.
+var errUsage = errors.New("no command given")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		usage(os.Stderr)
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "goiforest: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q, run goiforest help for usage", args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: goiforest <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// newFlagSet returns a flag set for the named command that reports errors
+// rather than exiting.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: goiforest %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// requireFlags returns an error naming the first of the given flags that is
+// empty.
+func requireFlags(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if fs.Lookup(name).Value.String() == "" {
+			return fmt.Errorf("%s: -%s is required", fs.Name(), name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mikemherron/goiforest"
+)
+
+func writeTestCSV(t *testing.T, dir string) string {
+	var sb strings.Builder
+	sb.WriteString("id,x,y,color\n")
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		y := fmt.Sprintf("%.3f", r.NormFloat64())
+		switch i % 50 {
+		case 7:
+			y = "NA"
+		case 9:
+			y = "bad"
+		}
+		fmt.Fprintf(&sb, "%d,%.3f,%s,%s\n", i, r.NormFloat64(), y, []string{"red", "blue"}[r.Intn(2)])
+	}
+	path := filepath.Join(dir, "data.csv")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTrainScoreInspect(t *testing.T) {
+	dir := t.TempDir()
+	data := writeTestCSV(t, dir)
+	model := filepath.Join(dir, "model.bin")
+	scored := filepath.Join(dir, "scored.csv")
+
+	var out, stderr bytes.Buffer
+	if err := run([]string{"train", "-in", data, "-out", model, "-exclude", "id", "-schema", "y:numerical",
+		"-trees", "20", "-seed", "3"}, &out, &stderr); err == nil {
+		t.Fatalf("Expected error training on invalid values")
+	}
+	if err := run([]string{"train", "-in", data, "-out", model, "-exclude", "id", "-schema", "y:numerical",
+		"-on-error", "skip", "-trees", "20", "-seed", "3", "-split", "sciforest", "-unseen", "anomalous"}, &out, &stderr); err != nil {
+		t.Fatalf("Unexpected error training: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "skipped 6 rows") {
+		t.Errorf("Expected a report of skipped rows, got %q", stderr.String())
+	}
+	if err := run([]string{"score", "-model", model, "-in", data, "-out", scored, "-path-lengths",
+		"-on-error", "missing"}, &out, io.Discard); err != nil {
+		t.Fatalf("Unexpected error scoring: %v", err)
+	}
+
+	file, err := os.Open(scored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 301 {
+		t.Fatalf("Expected 301 records, got %d", len(records))
+	}
+	expected := []string{"id", "x", "y", "color", "score", "anomaly", "path_length"}
+	if !reflect.DeepEqual(records[0], expected) {
+		t.Errorf("Expected header %v, got %v", expected, records[0])
+	}
+
+	out.Reset()
+	if err := run([]string{"inspect", "-model", model}, &out, io.Discard); err != nil {
+		t.Fatalf("Unexpected error inspecting: %v", err)
+	}
+	for _, want := range []string{"Trees:", "20", "Seed:", "sciforest", "anomalous", "color", "categorical"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected inspect output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestScoreSkippedAndShortRows(t *testing.T) {
+	dir := t.TempDir()
+	var sb strings.Builder
+	sb.WriteString("id,x\n")
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, "%d,%.3f\n", i, r.NormFloat64())
+	}
+	data := filepath.Join(dir, "data.csv")
+	model := filepath.Join(dir, "model.bin")
+	if err := os.WriteFile(data, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"train", "-in", data, "-out", model, "-exclude", "id", "-trees", "50", "-seed", "3"},
+		io.Discard, io.Discard); err != nil {
+		t.Fatalf("Unexpected error training: %v", err)
+	}
+
+	cases := []struct {
+		policy string
+		input  string
+		// scored lists, for each record after the header, whether it is
+		// expected to have a score and whether it is anomalous.
+		scored    []bool
+		anomalous []bool
+	}{
+		{
+			policy:    "skip",
+			input:     "id,x\na,0.1\nb,oops\nc,0.1\nd,50\n",
+			scored:    []bool{true, false, true, true},
+			anomalous: []bool{false, false, false, true},
+		},
+		{
+			policy:    "missing",
+			input:     "id,x\na,0.1\nb\nc,50\n",
+			scored:    []bool{true, true, true},
+			anomalous: []bool{false, false, true},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.policy, func(t *testing.T) {
+			in := filepath.Join(dir, c.policy+".csv")
+			if err := os.WriteFile(in, []byte(c.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := run([]string{"score", "-model", model, "-in", in, "-on-error", c.policy},
+				&out, io.Discard); err != nil {
+				t.Fatalf("Unexpected error scoring: %v", err)
+			}
+
+			records, err := csv.NewReader(&out).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != len(c.scored)+1 {
+				t.Fatalf("Expected %d records, got %v", len(c.scored)+1, records)
+			}
+			for i, record := range records[1:] {
+				if len(record) != 4 || record[0] != string(rune('a'+i)) {
+					t.Errorf("Expected record %d to be row %c with 4 fields, got %v", i, 'a'+i, record)
+					continue
+				}
+				if (record[2] != "") != c.scored[i] {
+					t.Errorf("Expected record %v to be scored: %t", record, c.scored[i])
+				}
+				if (record[3] == "true") != c.anomalous[i] {
+					t.Errorf("Expected record %v to be anomalous: %t", record, c.anomalous[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaFlags(t *testing.T) {
+	dir := t.TempDir()
+	data := writeTestCSV(t, dir)
+	schema := filepath.Join(dir, "schema.json")
+
+	flags := dataFlags{in: data, schema: "id:categorical", exclude: "y", saveSchema: schema,
+		csvFlags: csvFlags{onError: "fail"}}
+	if _, err := flags.load(io.Discard); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	saved, err := loadSchema(schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := goiforest.Schema{
+		"id":    goiforest.AttributeTypeCategorical,
+		"x":     goiforest.AttributeTypeNumerical,
+		"color": goiforest.AttributeTypeCategorical,
+	}
+	if !reflect.DeepEqual(saved, expected) {
+		t.Errorf("Expected %v, got %v", expected, saved)
+	}
+
+	flags = dataFlags{in: data, schemaFile: schema, exclude: "id", csvFlags: csvFlags{onError: "fail"}}
+	dataSet, err := flags.load(io.Discard)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(dataSet.Attributes) != 2 || dataSet.Size != 300 {
+		t.Errorf("Expected 2 attributes and 300 rows, got %v and %d", dataSet.Attributes, dataSet.Size)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	var out bytes.Buffer
+	for _, args := range [][]string{
+		{"unknown"},
+		{"train", "-in", "missing.csv"},
+		{"train", "-in", "data.csv", "-out", "model.bin", "-split", "best"},
+		{"train", "-in", "data.csv", "-out", "model.bin", "-unseen", "ignore"},
+		{"score", "-model", "missing.bin", "-in", "missing.csv"},
+		{"stats"},
+		{"stats", "-in", "data.csv", "-on-error", "ignore"},
+	} {
+		if err := run(args, &out, io.Discard); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+
+	if _, err := parseSchema("x:float"); err == nil {
+		t.Errorf("Expected error parsing unknown attribute type")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/mikemherron/goiforest"
+)
+
+func runScore(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("score", "-model model.bin -in data.csv [-out scored.csv] [flags]")
+	model := fs.String("model", "", "model `file` saved by train")
+	in := fs.String("in", "", "input CSV `file` with a header row containing the model's attributes")
+	out := fs.String("out", "", "output CSV `file`, standard output if empty")
+	var csvFlags csvFlags
+	csvFlags.register(fs)
+	pathLengths := fs.Bool("path-lengths", false, "add an average path length column")
+	workers := fs.Int("workers", 0, "rows scored concurrently, GOMAXPROCS if zero")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "model", "in"); err != nil {
+		return err
+	}
+
+	csvOpts, err := csvFlags.options()
+	if err != nil {
+		return err
+	}
+
+	forest, err := loadModel(*model)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	dataSet, err := forestDataSet(forest, data, csvOpts)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", *in, err)
+	}
+	printReport(stderr, *in, csvOpts.Report)
+
+	result, err := forest.ScoreDataSet(dataSet, goiforest.BatchOptions{Workers: *workers, PathLengths: *pathLengths})
+	if err != nil {
+		return err
+	}
+
+	lines := csvOpts.Report.Lines
+	if *out == "" {
+		return writeScores(stdout, csv.NewReader(bytes.NewReader(data)), lines, result, forest.Threshold())
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = writeScores(file, csv.NewReader(bytes.NewReader(data)), lines, result, forest.Threshold())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeScores copies the records read from r to w, adding score, anomaly and,
+// if computed, average path length columns. lines holds the line each scored
+// row was read from, as in CSVReport.Lines. Records on other lines were
+// skipped when loading and get empty score columns. Records are padded or cut
+// to the width of the header, and records too malformed to read are left out.
+func writeScores(w io.Writer, r *csv.Reader, lines []int, result *goiforest.BatchResult, threshold float64) error {
+	r.FieldsPerRecord = -1
+	cw := csv.NewWriter(w)
+	header, err := r.Read()
+	if err != nil {
+		return err
+	}
+	width := len(header)
+	header = append(header, "score", "anomaly")
+	if result.AveragePathLengths != nil {
+		header = append(header, "path_length")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	row := 0
+	for {
+		record, err := r.Read()
+		var parseErr *csv.ParseError
+		if errors.Is(err, io.EOF) {
+			break
+		} else if errors.As(err, &parseErr) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if len(record) < width {
+			record = append(record, make([]string, width-len(record))...)
+		}
+		record = record[:width]
+
+		line, _ := r.FieldPos(0)
+		if row < len(lines) && lines[row] == line {
+			score := result.Scores[row]
+			record = append(record,
+				strconv.FormatFloat(score, 'f', 6, 64),
+				strconv.FormatBool(score > threshold))
+			if result.AveragePathLengths != nil {
+				record = append(record, strconv.FormatFloat(result.AveragePathLengths[row], 'f', 6, 64))
+			}
+			row++
+		} else {
+			record = append(record, make([]string, len(header)-width)...)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func loadModel(path string) (*goiforest.IsolationForest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	forest, err := goiforest.Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("error loading model %s: %w", path, err)
+	}
+	return forest, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mikemherron/goiforest/server"
+)
+
+func runServe(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("serve", "-model model.bin [-addr :8080] [flags]")
+	model := fs.String("model", "", "model `file` saved by train")
+	addr := fs.String("addr", ":8080", "`address` to listen on")
+	watch := fs.Duration("watch", 0, "reload the model when its file changes, checking at this `interval`; "+
+		"the model is also reloaded on SIGHUP")
+	missing := fs.String("missing", "NA,NaN,null", "comma separated `tokens` read as missing values, "+
+		"in addition to null and empty values")
+	maxBatch := fs.Int("max-batch", server.DefaultMaxBatchSize, "largest number of points scored in one request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "model"); err != nil {
+		return err
+	}
+
+	s, err := server.NewFromFile(*model, server.Options{
+		MissingTokens: splitList(*missing),
+		MaxBatchSize:  *maxBatch,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logError := func(err error) {
+		fmt.Fprintf(stderr, "error reloading %s: %v\n", *model, err)
+	}
+	if *watch > 0 {
+		go s.Watch(ctx, *watch, logError)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				if err := s.Reload(); err != nil {
+					logError(err)
+				} else {
+					fmt.Fprintf(stdout, "Reloaded %s\n", *model)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	httpServer := &http.Server{Addr: *addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.ListenAndServe()
+	}()
+	fmt.Fprintf(stdout, "Serving %s (%d trees) on %s\n", *model, len(s.Forest().Trees), *addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
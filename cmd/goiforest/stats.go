@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+func runStats(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("stats", "-in data.csv [flags]")
+	var data dataFlags
+	data.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "in"); err != nil {
+		return err
+	}
+
+	dataSet, err := data.load(stderr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Rows: %d\n\n", dataSet.Size)
+	fmt.Fprint(stdout, dataSet.Stats())
+	return nil
+}
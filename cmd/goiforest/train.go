@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mikemherron/goiforest"
+)
+
+func runTrain(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("train", "-in data.csv -out model.bin [flags]")
+	var data dataFlags
+	data.register(fs)
+	out := fs.String("out", "", "output model `file`")
+	asJSON := fs.Bool("json", false, "save the model as JSON rather than binary")
+
+	defaults := goiforest.DefaultForestConfig()
+	config := goiforest.ForestConfig{}
+	fs.IntVar(&config.NumTrees, "trees", defaults.NumTrees, "number of trees")
+	fs.IntVar(&config.SampleSize, "sample", defaults.SampleSize,
+		"rows sampled per tree, reduced to the number of rows if larger")
+	fs.IntVar(&config.MaxDepth, "max-depth", 0, "maximum tree depth, derived from the sample size if zero")
+	fs.Int64Var(&config.Seed, "seed", 0, "random seed, chosen at random if zero")
+	fs.IntVar(&config.Workers, "workers", 0, "trees built concurrently, GOMAXPROCS if zero")
+	fs.BoolVar(&config.Extended, "extended", false, "build an Extended Isolation Forest")
+	fs.IntVar(&config.ExtensionLevel, "extension-level", 0, "extension level of an extended forest")
+	split := fs.String("split", "random", "split `strategy`: random, uniform to draw categories uniformly "+
+		"from the distinct values rather than from a random row, sets to split categories into random sets, "+
+		"or sciforest")
+	unseen := fs.String("unseen", "follow", "how categories not seen in training are scored: "+
+		"follow the splits, score as anomalous, route randomly, or error")
+	fs.Float64Var(&config.Contamination, "contamination", 0,
+		"expected proportion of anomalies, used to set the threshold")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "in", "out"); err != nil {
+		return err
+	}
+
+	strategy, err := splitStrategy(*split)
+	if err != nil {
+		return err
+	}
+	config.SplitStrategy = strategy
+	if config.UnseenCategories, err = goiforest.ParseUnseenCategoryPolicy(*unseen); err != nil {
+		return err
+	}
+
+	dataSet, err := data.load(stderr)
+	if err != nil {
+		return err
+	}
+	if config.SampleSize > dataSet.Size {
+		config.SampleSize = dataSet.Size
+	}
+
+	forest, err := goiforest.BuildForestWithConfig(dataSet, config)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		err = forest.SaveJSON(file)
+	} else {
+		err = forest.Save(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error saving model: %w", err)
+	}
+
+	fmt.Fprintf(stdout, "Trained %d trees on %d rows and %d attributes (seed %d), saved to %s\n",
+		len(forest.Trees), dataSet.Size, len(dataSet.Attributes), forest.Config().Seed, *out)
+	return nil
+}
+
+// splitStrategy returns the strategy named by the split flag, or nil for the
+// default.
+func splitStrategy(name string) (goiforest.SplitStrategy, error) {
+	switch name {
+	case "random":
+		return nil, nil
+	case "uniform":
+		return goiforest.RandomSplit{UniformCategories: true}, nil
+	case "sets":
+		return goiforest.RandomSplit{CategorySets: true}, nil
+	case "sciforest":
+		return goiforest.SCiForestSplit{}, nil
+	}
+	return nil, fmt.Errorf("unknown -split strategy %q, expected random, uniform, sets or sciforest", name)
+}
+
+// strategyName describes a split strategy for inspect.
+func strategyName(strategy goiforest.SplitStrategy) string {
+	switch strategy := strategy.(type) {
+	case nil:
+		return "random"
+	case goiforest.RandomSplit:
+		switch {
+		case strategy.CategorySets && strategy.UniformCategories:
+			return "sets, uniform"
+		case strategy.CategorySets:
+			return "sets"
+		case strategy.UniformCategories:
+			return "uniform"
+		}
+		return "random"
+	case goiforest.SCiForestSplit:
+		return fmt.Sprintf("sciforest (%d candidates, %d attributes)", strategy.Candidates, strategy.Attributes)
+	}
+	return fmt.Sprintf("%T", strategy)
+}
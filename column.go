@@ -0,0 +1,126 @@
+package goiforest
+
+import "sync/atomic"
+
+// column stores the values of a single attribute contiguously. Numerical
+// values are held in num and categorical values are dictionary encoded in
+// codes. missing is nil until the first missing value is added.
+type column struct {
+	typ     AttributeType
+	num     []float64
+	codes   []uint32
+	dict    *dictionary
+	missing []bool
+}
+
+func newColumn(typ AttributeType, capacity int) *column {
+	c := &column{typ: typ}
+	if typ == AttributeTypeCategorical {
+		c.codes = make([]uint32, 0, capacity)
+		c.dict = newDictionary()
+	} else {
+		c.num = make([]float64, 0, capacity)
+	}
+	return c
+}
+
+func (c *column) len() int {
+	if c.typ == AttributeTypeCategorical {
+		return len(c.codes)
+	}
+	return len(c.num)
+}
+
+func (c *column) isMissing(i int) bool {
+	return c.missing != nil && c.missing[i]
+}
+
+func (c *column) value(i int) AttributeValue {
+	if c.isMissing(i) {
+		return AttributeValue{Missing: true}
+	}
+	if c.typ == AttributeTypeCategorical {
+		return AttributeValue{Str: c.dict.values[c.codes[i]]}
+	}
+	return AttributeValue{Num: c.num[i]}
+}
+
+func (c *column) append(v AttributeValue) {
+	if c.typ == AttributeTypeCategorical {
+		var code uint32
+		if !v.Missing {
+			code = c.dict.code(v.Str)
+		}
+		c.codes = append(c.codes, code)
+	} else {
+		c.num = append(c.num, v.Num)
+	}
+
+	if v.Missing && c.missing == nil {
+		c.missing = make([]bool, c.len()-1, cap(c.num)+cap(c.codes))
+	}
+	if c.missing != nil {
+		c.missing = append(c.missing, v.Missing)
+	}
+}
+
+func (c *column) set(i int, v AttributeValue) {
+	if c.typ == AttributeTypeCategorical {
+		if !v.Missing {
+			c.codes[i] = c.dict.code(v.Str)
+		}
+	} else {
+		c.num[i] = v.Num
+	}
+
+	if v.Missing && c.missing == nil {
+		c.missing = make([]bool, c.len())
+	}
+	if c.missing != nil {
+		c.missing[i] = v.Missing
+	}
+}
+
+// dictionary maps categorical values to dense codes.
+type dictionary struct {
+	values []string
+	codes  map[string]uint32
+}
+
+func newDictionary() *dictionary {
+	return &dictionary{codes: map[string]uint32{}}
+}
+
+func (d *dictionary) code(value string) uint32 {
+	code, ok := d.codes[value]
+	if !ok {
+		code = uint32(len(d.values))
+		d.values = append(d.values, value)
+		d.codes[value] = code
+	}
+	return code
+}
+
+// storageFlag is shared by data sets whose columns are shared, so that any of
+// them can tell it must copy its columns before modifying them.
+type storageFlag struct {
+	shared atomic.Bool
+}
+
+// attributeValues is a row held as a slice of values.
+type attributeValues []AttributeValue
+
+func (v attributeValues) Value(i int) AttributeValue {
+	return v[i]
+}
+
+// dataSetRow reads a row of a data set directly from its columns. It is
+// passed by pointer and reused across rows to avoid allocating.
+type dataSetRow struct {
+	d   *DataSet
+	row int
+}
+
+func (r *dataSetRow) Value(i int) AttributeValue {
+	return r.d.columns[i].value(r.d.storeRow(r.row))
+}
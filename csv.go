@@ -0,0 +1,314 @@
+package goiforest
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultMissingTokens are values commonly used in CSV files to indicate a
+// missing value.
+var DefaultMissingTokens = []string{"", "NA", "NaN", "null"}
+
+// CSVErrorPolicy controls how values and rows of a CSV file that cannot be
+// read are handled.
+type CSVErrorPolicy int
+
+const (
+	// CSVErrorFail stops reading and returns the error.
+	CSVErrorFail CSVErrorPolicy = iota
+	// CSVErrorSkipRow leaves out rows with an error.
+	CSVErrorSkipRow
+	// CSVErrorMissing reads values that cannot be parsed, and the values
+	// absent from rows with too few fields, as missing. Rows that cannot be
+	// read at all, such as those with a bare quote, are left out.
+	CSVErrorMissing
+)
+
+// CSVOptions controls NewDataSetFromCSVWithOptions.
+type CSVOptions struct {
+	// MissingTokens lists values, compared after trimming white space, that
+	// are read as missing rather than parsed. If empty, no value is treated
+	// as missing.
+	MissingTokens []string
+	// OnError controls how values that cannot be parsed and malformed rows
+	// are handled.
+	OnError CSVErrorPolicy
+	// Report, if not nil, is filled with a summary of the rows read and the
+	// errors handled according to OnError.
+	Report *CSVReport
+}
+
+func (o CSVOptions) isMissing(value string) bool {
+	return isMissingToken(value, o.MissingTokens)
+}
+
+func isMissingToken(value string, tokens []string) bool {
+	value = strings.TrimSpace(value)
+	for _, token := range tokens {
+		if value == token {
+			return true
+		}
+	}
+	return false
+}
+
+// CSVError describes a row or value of a CSV file that could not be read.
+// Column and Value are empty if the whole row could not be read.
+type CSVError struct {
+	Line   int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *CSVError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// MaxCSVReportErrors is the number of errors kept in a CSVReport.
+const MaxCSVReportErrors = 100
+
+// CSVReport summarises the rows read from a CSV file.
+type CSVReport struct {
+	// Rows is the number of rows read into the data set.
+	Rows int
+	// SkippedRows is the number of rows left out because of errors.
+	SkippedRows int
+	// MissingValues is the number of values read as missing because of
+	// errors. Values matching a missing token are not counted.
+	MissingValues int
+	// Errors holds the first MaxCSVReportErrors errors handled, in order.
+	Errors []*CSVError
+	// Lines holds the line each row of the data set was read from, so rows
+	// can be matched to the records of the file when some were skipped.
+	Lines []int
+}
+
+func (r *CSVReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "read %d rows, skipped %d rows, read %d invalid values as missing",
+		r.Rows, r.SkippedRows, r.MissingValues)
+	for _, err := range r.Errors {
+		fmt.Fprintf(&sb, "\n  %v", err)
+	}
+	return sb.String()
+}
+
+// skipRow records a row left out because of err. Like missingValues, it does
+// nothing if r is nil.
+func (r *CSVReport) skipRow(err *CSVError) {
+	if r == nil {
+		return
+	}
+	r.SkippedRows++
+	r.add(err)
+}
+
+// missingValues records n values read as missing because of err.
+func (r *CSVReport) missingValues(err *CSVError, n int) {
+	if r == nil {
+		return
+	}
+	r.MissingValues += n
+	r.add(err)
+}
+
+func (r *CSVReport) add(err *CSVError) {
+	if len(r.Errors) < MaxCSVReportErrors {
+		r.Errors = append(r.Errors, err)
+	}
+}
+
+func NewDataSetFromCSV(r *csv.Reader, attributes map[string]AttributeType) (*DataSet, error) {
+	return NewDataSetFromCSVWithOptions(r, attributes, CSVOptions{})
+}
+
+// NewDataSetFromCSVWithOptions reads the named attributes from r, which must
+// start with a header row. Errors reading rows are handled according to
+// opts.OnError; those returned are *CSVError values giving the line, and the
+// column and value if a single value could not be parsed.
+func NewDataSetFromCSVWithOptions(r *csv.Reader, attributes map[string]AttributeType, opts CSVOptions) (*DataSet, error) {
+	header, err := readCSVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return newDataSetFromRecords(header, csvRecords(r), attributes, opts)
+}
+
+func readCSVHeader(r *csv.Reader) ([]string, error) {
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("empty CSV file")
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+	return header, nil
+}
+
+// csvRecord is a record read from a CSV file, with the line it starts on and
+// any error reading it.
+type csvRecord struct {
+	fields []string
+	line   int
+	err    error
+}
+
+// csvRecords returns a function reading the records of r in turn. It returns
+// a record with io.EOF as its error after the last one.
+func csvRecords(r *csv.Reader) func() csvRecord {
+	return func() csvRecord {
+		fields, err := r.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return csvRecord{fields: fields, line: parseErr.StartLine, err: parseErr.Err}
+		} else if err != nil {
+			return csvRecord{err: err}
+		}
+		line, _ := r.FieldPos(0)
+		return csvRecord{fields: fields, line: line}
+	}
+}
+
+// newDataSetFromRecords reads a data set from the CSV records returned by
+// next.
+func newDataSetFromRecords(header []string, next func() csvRecord, attributes map[string]AttributeType, opts CSVOptions) (*DataSet, error) {
+	ds := NewDataSet()
+	report := opts.Report
+	if report != nil {
+		*report = CSVReport{}
+	}
+
+	remainingAttributes := map[string]bool{}
+	for name := range attributes {
+		remainingAttributes[name] = true
+	}
+
+	attributeIdx := map[int]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if _, ok := attributes[name]; !ok {
+			continue
+		}
+
+		delete(remainingAttributes, name)
+
+		attributeIdx[i] = len(ds.Attributes)
+		ds.AddAttribute(Attribute{
+			Name: name,
+			Type: attributes[name],
+		})
+	}
+
+	if len(remainingAttributes) > 0 {
+		return nil, fmt.Errorf("one more attributes not found in CSV file: %v", remainingAttributes)
+	}
+
+	values := make([]AttributeValue, len(ds.Attributes))
+	for {
+		record := next()
+		if errors.Is(record.err, io.EOF) {
+			break
+		}
+
+		if record.err != nil && !errors.Is(record.err, csv.ErrFieldCount) {
+			if record.line == 0 {
+				return nil, fmt.Errorf("error reading CSV row: %w", record.err)
+			}
+			rowErr := &CSVError{Line: record.line, Err: record.err}
+			if opts.OnError == CSVErrorFail {
+				return nil, rowErr
+			}
+			report.skipRow(rowErr)
+			continue
+		}
+
+		if len(record.fields) != len(header) {
+			rowErr := &CSVError{Line: record.line, Err: csv.ErrFieldCount}
+			switch opts.OnError {
+			case CSVErrorFail:
+				return nil, rowErr
+			case CSVErrorSkipRow:
+				report.skipRow(rowErr)
+				continue
+			}
+			absent := 0
+			for i := range attributeIdx {
+				if i >= len(record.fields) {
+					absent++
+				}
+			}
+			report.missingValues(rowErr, absent)
+		}
+
+		ok, err := readCSVValues(ds.Attributes, record, attributeIdx, values, opts)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		for idx, value := range values {
+			ds.columns[idx].append(value)
+		}
+		ds.Size++
+		if report != nil {
+			report.Lines = append(report.Lines, record.line)
+		}
+	}
+
+	if report != nil {
+		report.Rows = ds.Size
+	}
+	return ds, nil
+}
+
+// readCSVValues parses the fields of record into values, which are in the
+// order of attributes, handling errors according to opts.OnError. Absent
+// values are missing. It reports false if the row must be skipped.
+func readCSVValues(attributes []Attribute, record csvRecord, attributeIdx map[int]int, values []AttributeValue, opts CSVOptions) (bool, error) {
+	for i := range values {
+		values[i] = AttributeValue{Missing: true}
+	}
+
+	for i, value := range record.fields {
+		idx, ok := attributeIdx[i]
+		if !ok || opts.isMissing(value) {
+			continue
+		}
+		attribute := attributes[idx]
+
+		if attribute.Type == AttributeTypeCategorical {
+			values[idx] = AttributeValue{Str: strings.TrimSpace(value)}
+			continue
+		}
+
+		num, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			values[idx] = AttributeValue{Num: num}
+			continue
+		}
+
+		valueErr := &CSVError{
+			Line:   record.line,
+			Column: attribute.Name,
+			Value:  value,
+			Err:    &ParseError{Attribute: attribute.Name, Value: value, Err: err},
+		}
+		switch opts.OnError {
+		case CSVErrorFail:
+			return false, valueErr
+		case CSVErrorSkipRow:
+			opts.Report.skipRow(valueErr)
+			return false, nil
+		}
+		opts.Report.missingValues(valueErr, 1)
+	}
+	return true, nil
+}
@@ -0,0 +1,90 @@
+package goiforest
+
+import (
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dirtyCSV = `Name,Cost
+apple,0.5
+banana,cheap
+pear
+plum,0.75,extra
+"fig,1.2
+`
+
+var dirtyAttributes = map[string]AttributeType{
+	"Name": AttributeTypeCategorical,
+	"Cost": AttributeTypeNumerical,
+}
+
+func TestCSVErrorFail(t *testing.T) {
+	_, err := NewDataSetFromCSV(csv.NewReader(strings.NewReader(dirtyCSV)), dirtyAttributes)
+
+	var csvErr *CSVError
+	if !errors.As(err, &csvErr) {
+		t.Fatalf("Expected *CSVError, got %v", err)
+	}
+	if csvErr.Line != 3 || csvErr.Column != "Cost" || csvErr.Value != "cheap" {
+		t.Errorf("Expected error at line 3, column Cost, value cheap, got %+v", csvErr)
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("Expected error to wrap *ParseError, got %v", err)
+	}
+}
+
+func TestCSVErrorSkipRow(t *testing.T) {
+	var report CSVReport
+	ds, err := NewDataSetFromCSVWithOptions(csv.NewReader(strings.NewReader(dirtyCSV)), dirtyAttributes,
+		CSVOptions{OnError: CSVErrorSkipRow, Report: &report})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ds.Size != 1 || report.Rows != 1 || report.SkippedRows != 4 || report.MissingValues != 0 {
+		t.Errorf("Expected 1 row read and 4 skipped, got size %d and report %v", ds.Size, &report)
+	}
+	lines := []int{3, 4, 5, 6}
+	if len(report.Errors) != len(lines) {
+		t.Fatalf("Expected %d errors, got %v", len(lines), report.Errors)
+	}
+	for i, line := range lines {
+		if report.Errors[i].Line != line {
+			t.Errorf("Expected error %d on line %d, got %v", i, line, report.Errors[i])
+		}
+	}
+	if !errors.Is(report.Errors[1], csv.ErrFieldCount) {
+		t.Errorf("Expected field count error, got %v", report.Errors[1])
+	}
+	if !reflect.DeepEqual(report.Lines, []int{2}) {
+		t.Errorf("Expected the row read from line 2, got lines %v", report.Lines)
+	}
+}
+
+func TestCSVErrorMissing(t *testing.T) {
+	var report CSVReport
+	ds, err := NewDataSetFromCSVWithOptions(csv.NewReader(strings.NewReader(dirtyCSV)), dirtyAttributes,
+		CSVOptions{OnError: CSVErrorMissing, Report: &report})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ds.Size != 4 || report.SkippedRows != 1 || report.MissingValues != 2 {
+		t.Errorf("Expected 4 rows, 1 skipped and 2 missing values, got size %d and report %v", ds.Size, &report)
+	}
+
+	cost := Attribute{Name: "Cost", Type: AttributeTypeNumerical}
+	expected := []AttributeValue{{Num: 0.5}, {Missing: true}, {Missing: true}, {Num: 0.75}}
+	for i, v := range expected {
+		if actual := ds.Value(i, cost); actual != v {
+			t.Errorf("Row %d: expected %v, got %v", i, v, actual)
+		}
+	}
+	if !reflect.DeepEqual(report.Lines, []int{2, 3, 4, 5}) {
+		t.Errorf("Expected rows read from lines 2 to 5, got lines %v", report.Lines)
+	}
+}
@@ -24,9 +24,15 @@ type Attribute struct {
 type AttributeValue struct {
 	Str string
 	Num float64
+	// Missing is set when the value is unknown, in which case Str and Num
+	// are ignored.
+	Missing bool
 }
 
 func (a Attribute) ValueToString(v AttributeValue) string {
+	if v.Missing {
+		return ""
+	}
 	if a.Type == AttributeTypeCategorical {
 		return v.Str
 	} else if a.Type == AttributeTypeNumerical {
@@ -35,103 +41,91 @@ func (a Attribute) ValueToString(v AttributeValue) string {
 	panic("Unknown feature type")
 }
 
+var ErrUnknownAttributeType = errors.New("unknown attribute type")
+
+// MissingAttributeError is returned when a data point or data set does not
+// contain an attribute required by a forest.
+type MissingAttributeError struct {
+	Attribute string
+}
+
+func (e *MissingAttributeError) Error() string {
+	return fmt.Sprintf("attribute %s not found", e.Attribute)
+}
+
+// ParseError is returned when a value cannot be parsed as the type of its
+// attribute.
+type ParseError struct {
+	Attribute string
+	Value     string
+	Err       error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("error parsing value %q of attribute %s: %v", e.Value, e.Attribute, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// NewAttributeValue is like ParseAttributeValue but panics on error.
 func NewAttributeValue(f Attribute, v string) AttributeValue {
+	val, err := ParseAttributeValue(f, v)
+	if err != nil {
+		panic(err.Error())
+	}
+	return val
+}
+
+// ParseAttributeValue converts v to a value of attribute f. It returns a
+// *ParseError if a numerical value cannot be parsed, or an error wrapping
+// ErrUnknownAttributeType if f has an unknown type.
+func ParseAttributeValue(f Attribute, v string) (AttributeValue, error) {
 	if f.Type == AttributeTypeCategorical {
-		return AttributeValue{Str: v}
+		return AttributeValue{Str: v}, nil
 	} else if f.Type == AttributeTypeNumerical {
 		num, err := strconv.ParseFloat(v, 64)
 		if err != nil {
-			panic(fmt.Sprintf("Error parsing value %v as float", v))
+			return AttributeValue{}, &ParseError{Attribute: f.Name, Value: v, Err: err}
 		}
-		return AttributeValue{Num: num}
+		return AttributeValue{Num: num}, nil
 	}
-	panic("Unknown feature type")
+	return AttributeValue{}, fmt.Errorf("%w %d for attribute %s", ErrUnknownAttributeType, f.Type, f.Name)
 }
 
+// DataSet holds rows of attribute values in typed columns, one per attribute.
+// Data sets returned by Sample, Filter, Limit, Shuffle, With, Excluding and
+// Split are views that share their columns with the data set they came from
+// and select rows by index, so they are cheap to create. A data set copies its
+// columns before it is first modified while they are shared, so views behave
+// like independent copies.
 type DataSet struct {
 	Attributes []Attribute
-	Values     map[Attribute][]AttributeValue
 	Size       int
+	columns    []*column
+	// rows maps each row of the data set to a row of its columns. It is nil
+	// if the rows of the data set are the rows of its columns.
+	rows   []int
+	shared *storageFlag
 }
 
 func NewDataSet() *DataSet {
 	return &DataSet{
 		Attributes: []Attribute{},
-		Values:     map[Attribute][]AttributeValue{},
+		shared:     &storageFlag{},
 	}
 }
 
-func NewDataSetFromCSV(r *csv.Reader, attributes map[string]AttributeType) (*DataSet, error) {
-	header, err := r.Read()
-	if errors.Is(err, io.EOF) {
-		return nil, fmt.Errorf("empty CSV file")
-	} else if err != nil {
-		return nil, fmt.Errorf("error reading CSV header: %w", err)
-	}
-
-	ds := DataSet{
-		Values:     map[Attribute][]AttributeValue{},
-		Attributes: make([]Attribute, 0, len(header)),
-	}
-
-	remainingAttributes := map[string]bool{}
-	for name := range attributes {
-		remainingAttributes[name] = true
+// AddAttribute adds an attribute to an empty data set.
+func (d *DataSet) AddAttribute(a Attribute) {
+	if d.Size > 0 {
+		panic("Cannot add attribute to non-empty dataset")
 	}
 
-	attributeIdx := map[int]Attribute{}
-	for i, name := range header {
-		name = strings.TrimSpace(name)
-		if _, ok := attributes[name]; !ok {
-			continue
-		}
-
-		delete(remainingAttributes, name)
-
-		attribute := Attribute{
-			Name: name,
-			Type: attributes[name],
-		}
-		ds.Attributes = append(ds.Attributes, attribute)
-		ds.Values[attribute] = []AttributeValue{}
-
-		attributeIdx[i] = attribute
-	}
-
-	if len(remainingAttributes) > 0 {
-		return nil, fmt.Errorf("one more attributes not found in CSV file: %v", remainingAttributes)
-	}
-
-	for {
-		record, err := r.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		} else if err != nil {
-			return nil, fmt.Errorf("error reading CSV row: %w", err)
-		}
-
-		for i, value := range record {
-			attribute, ok := attributeIdx[i]
-			if !ok {
-				continue
-			}
-
-			attributeValue := AttributeValue{}
-			if attribute.Type == AttributeTypeNumerical {
-				attributeValue.Num, err = strconv.ParseFloat(value, 64)
-				if err != nil {
-					return nil, fmt.Errorf("error parsing value %v as float", value)
-				}
-			} else if attribute.Type == AttributeTypeCategorical {
-				attributeValue.Str = strings.TrimSpace(value)
-			}
-
-			ds.Values[attribute] = append(ds.Values[attribute], attributeValue)
-		}
-		ds.Size++
-	}
-
-	return &ds, nil
+	d.materialize()
+	d.Attributes = append(d.Attributes, a)
+	d.columns = append(d.columns, newColumn(a.Type, 0))
 }
 
 type DataSetStats struct {
@@ -175,10 +169,10 @@ type AttributeStats struct {
 
 func (d *DataSet) Stats() DataSetStats {
 	stats := DataSetStats{
-		Attributes: make([]AttributeStats, len(d.Attributes)),
+		Attributes: make([]AttributeStats, 0, len(d.Attributes)),
 	}
 
-	for _, attribute := range d.Attributes {
+	for i, attribute := range d.Attributes {
 		attributeStats := AttributeStats{
 			Attribute: attribute,
 		}
@@ -187,11 +181,16 @@ func (d *DataSet) Stats() DataSetStats {
 			continue
 		}
 
-		values := make([]float64, d.Size)
+		col := d.columns[i]
+		values := make([]float64, 0, d.Size)
 		unique := make(map[float64]bool, 0)
-		for _, v := range d.Values[attribute] {
-			values = append(values, v.Num)
-			unique[v.Num] = true
+		for j := 0; j < d.Size; j++ {
+			row := d.storeRow(j)
+			if col.isMissing(row) {
+				continue
+			}
+			values = append(values, col.num[row])
+			unique[col.num[row]] = true
 		}
 
 		attributeStats.Kurtosis = kurtosis(values)
@@ -206,36 +205,42 @@ func (d *DataSet) Stats() DataSetStats {
 }
 
 func (d *DataSet) Filter(f func(map[string]AttributeValue) bool) *DataSet {
-	cp := d.CopyNoValues()
+	rows := make([]int, 0)
 	for i := 0; i < d.Size; i++ {
-		row := d.GetRowWithNames(i)
-		if f(row) {
-			cp.AddRow(d.GetRow(i))
+		if f(d.GetRowWithNames(i)) {
+			rows = append(rows, d.storeRow(i))
 		}
 	}
-	return cp
+	return d.view(d.Attributes, d.columns, rows)
 }
 
 func (d *DataSet) Limit(n int) *DataSet {
-	cp := d.CopyNoValues()
 	if n > d.Size {
 		n = d.Size
 	}
-	for i := 0; i < n; i++ {
-		cp.AddRow(d.GetRow(i))
-	}
-	return cp
+	return d.view(d.Attributes, d.columns, d.storeRows()[:n])
+}
+
+// newRand returns a random source seeded from the global source, for use by
+// methods that do not take an explicit source.
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(rand.Int63()))
 }
 
 func (d *DataSet) Shuffle() *DataSet {
-	cp := d.Copy()
-	rand.Shuffle(d.Size, func(i, j int) {
-		for _, attr := range d.Attributes {
-			cp.Values[attr][i], cp.Values[attr][j] = cp.Values[attr][j], cp.Values[attr][i]
-		}
+	return d.ShuffleRand(newRand())
+}
+
+// ShuffleRand is like Shuffle but draws from r, so the same source state
+// always produces the same order.
+func (d *DataSet) ShuffleRand(r *rand.Rand) *DataSet {
+	rows := make([]int, d.Size)
+	copy(rows, d.storeRows())
+	r.Shuffle(d.Size, func(i, j int) {
+		rows[i], rows[j] = rows[j], rows[i]
 	})
 
-	return cp
+	return d.view(d.Attributes, d.columns, rows)
 }
 
 func (d *DataSet) Merge(dataSets ...*DataSet) (*DataSet, error) {
@@ -253,48 +258,47 @@ func (d *DataSet) Merge(dataSets ...*DataSet) (*DataSet, error) {
 }
 
 func (d *DataSet) Sample(size int) *DataSet {
+	return d.SampleRand(size, newRand())
+}
+
+// SampleRand is like Sample but draws from r, so the same source state always
+// produces the same sample.
+func (d *DataSet) SampleRand(size int, r *rand.Rand) *DataSet {
 	if size > d.Size {
 		size = d.Size
 	}
 
-	cp := d.CopyNoValues()
-	copied := map[int]bool{}
+	rows := make([]int, 0, size)
+	copied := make(map[int]bool, size)
 
 	for i := 0; i < size; i++ {
 		var randIdx int
 		idxValid := false
 		for !idxValid {
-			randIdx = rand.Intn(d.Size)
+			randIdx = r.Intn(d.Size)
 			idxValid = !copied[randIdx]
 		}
 		copied[randIdx] = true
-		//TODO: copy row here
-		cp.AddRow(d.GetRow(randIdx))
+		rows = append(rows, d.storeRow(randIdx))
 	}
 
-	return cp
+	return d.view(d.Attributes, d.columns, rows)
 }
 
 func (d *DataSet) With(attributes []string) (*DataSet, error) {
-	attributeSet := d.attributeSet()
-	dsCopy := NewDataSet()
-	for i, attrName := range attributes {
-		if _, ok := attributeSet[attrName]; !ok {
+	positions := d.attributePositions()
+	selected := make([]Attribute, 0, len(attributes))
+	columns := make([]*column, 0, len(attributes))
+	for _, attrName := range attributes {
+		i, ok := positions[attrName]
+		if !ok {
 			return nil, fmt.Errorf("attribute %v not found in dataset", attrName)
 		}
-		dsCopy.Attributes[i] = attributeSet[attrName]
-	}
-
-	for i := 0; i < d.Size; i++ {
-		row := d.GetRow(i)
-		rowCp := map[Attribute]AttributeValue{}
-		for _, attr := range dsCopy.Attributes {
-			rowCp[attr] = row[attr]
-		}
-		dsCopy.AddRow(rowCp)
+		selected = append(selected, d.Attributes[i])
+		columns = append(columns, d.columns[i])
 	}
 
-	return dsCopy, nil
+	return d.view(selected, columns, d.rows), nil
 }
 
 func (d *DataSet) Excluding(attributes ...string) (*DataSet, error) {
@@ -311,51 +315,105 @@ func (d *DataSet) Excluding(attributes ...string) (*DataSet, error) {
 		excludeSet[attr] = true
 	}
 
-	cp := NewDataSet()
-	for _, attr := range d.Attributes {
+	selected := make([]Attribute, 0, len(d.Attributes))
+	columns := make([]*column, 0, len(d.Attributes))
+	for i, attr := range d.Attributes {
 		if _, ok := excludeSet[attr.Name]; ok {
 			continue
 		}
-		cp.Attributes = append(cp.Attributes, attr)
-		cp.Values[attr] = []AttributeValue{}
+		selected = append(selected, attr)
+		columns = append(columns, d.columns[i])
 	}
 
-	for i := 0; i < d.Size; i++ {
-		row := d.GetRow(i)
-		newRow := map[Attribute]AttributeValue{}
-		for _, feature := range d.Attributes {
-			if _, ok := excludeSet[feature.Name]; ok {
-				continue
-			}
-			newRow[feature] = row[feature]
-		}
-		cp.AddRow(newRow)
+	return d.view(selected, columns, d.rows), nil
+}
+
+func (d *DataSet) CopyNoValues() *DataSet {
+	cp := NewDataSet()
+	for _, attribute := range d.Attributes {
+		cp.AddAttribute(attribute)
 	}
 
-	return cp, nil
+	return cp
 }
 
-func (d *DataSet) CopyNoValues() *DataSet {
+func (d *DataSet) Copy() *DataSet {
 	cp := &DataSet{
 		Attributes: make([]Attribute, len(d.Attributes)),
-		Values:     map[Attribute][]AttributeValue{},
-	}
-	for i, attribute := range d.Attributes {
-		cp.Attributes[i] = attribute
-		cp.Values[attribute] = []AttributeValue{}
+		Size:       d.Size,
+		columns:    d.copyColumns(),
+		shared:     &storageFlag{},
 	}
+	copy(cp.Attributes, d.Attributes)
 
 	return cp
 }
 
-func (d *DataSet) Copy() *DataSet {
-	cp := d.CopyNoValues()
-	for i := 0; i < d.Size; i++ {
-		row := d.GetRow(i)
-		cp.AddRow(row)
+// view returns a data set over the given attributes and columns, selecting
+// rows of the columns by index. rows is not copied and must not be modified
+// afterwards. If rows is nil, the view has the same rows as d.
+func (d *DataSet) view(attributes []Attribute, columns []*column, rows []int) *DataSet {
+	d.shared.shared.Store(true)
+
+	size := d.Size
+	if rows != nil {
+		size = len(rows)
 	}
 
-	return cp
+	return &DataSet{
+		Attributes: attributes,
+		Size:       size,
+		columns:    columns,
+		rows:       rows,
+		shared:     d.shared,
+	}
+}
+
+// storeRow returns the row of d's columns holding row i of d.
+func (d *DataSet) storeRow(i int) int {
+	if d.rows == nil {
+		return i
+	}
+	return d.rows[i]
+}
+
+// storeRows returns the rows of d's columns holding the rows of d, in order.
+// The result must not be modified.
+func (d *DataSet) storeRows() []int {
+	if d.rows != nil {
+		return d.rows
+	}
+	rows := make([]int, d.Size)
+	for i := range rows {
+		rows[i] = i
+	}
+	return rows
+}
+
+func (d *DataSet) copyColumns() []*column {
+	columns := make([]*column, len(d.columns))
+	for i, col := range d.columns {
+		columns[i] = newColumn(col.typ, d.Size)
+		for j := 0; j < d.Size; j++ {
+			columns[i].append(col.value(d.storeRow(j)))
+		}
+	}
+	return columns
+}
+
+// materialize gives d its own copy of its columns if they are shared, so it
+// can modify them.
+func (d *DataSet) materialize() {
+	if d.shared == nil {
+		d.shared = &storageFlag{}
+	}
+	if d.rows == nil && !d.shared.shared.Load() {
+		return
+	}
+
+	d.columns = d.copyColumns()
+	d.rows = nil
+	d.shared = &storageFlag{}
 }
 
 func (d *DataSet) containsAttributes(name []string) error {
@@ -402,35 +460,77 @@ func (d *DataSet) attributeSet() map[string]Attribute {
 	return attributes
 }
 
-// func (d *DataSet) AddAttribute(a Attribute) {
-// 	if d.Size > 0 {
-// 		panic("Cannot add attribute to non-empty dataset")
-// 	}
+func (d *DataSet) attributePositions() map[string]int {
+	positions := make(map[string]int, len(d.Attributes))
+	for i, attr := range d.Attributes {
+		positions[attr.Name] = i
+	}
+	return positions
+}
 
-// 	d.Attributes = append(d.Attributes, a)
-// 	d.Values[a] = []AttributeValue{}
-// }
+// attributePosition returns the position of attr in d.Attributes, or -1 if
+// d does not have it.
+func (d *DataSet) attributePosition(attr Attribute) int {
+	for i, a := range d.Attributes {
+		if a == attr {
+			return i
+		}
+	}
+	return -1
+}
+
+// Value returns the value of attr in row idx.
+func (d *DataSet) Value(idx int, attr Attribute) AttributeValue {
+	i := d.attributePosition(attr)
+	if i < 0 {
+		panic(fmt.Sprintf("Feature %v does not exist in dataset", attr))
+	}
+	return d.columns[i].value(d.storeRow(idx))
+}
+
+// SetValue sets the value of attr in row idx.
+func (d *DataSet) SetValue(idx int, attr Attribute, v AttributeValue) {
+	i := d.attributePosition(attr)
+	if i < 0 {
+		panic(fmt.Sprintf("Feature %v does not exist in dataset", attr))
+	}
+	d.materialize()
+	d.columns[i].set(idx, v)
+}
+
+// Values returns a copy of every value in the data set, by attribute.
+func (d *DataSet) Values() map[Attribute][]AttributeValue {
+	values := make(map[Attribute][]AttributeValue, len(d.Attributes))
+	for i, attr := range d.Attributes {
+		column := make([]AttributeValue, d.Size)
+		for j := range column {
+			column[j] = d.columns[i].value(d.storeRow(j))
+		}
+		values[attr] = column
+	}
+	return values
+}
 
 func (d *DataSet) GetRow(idx int) map[Attribute]AttributeValue {
 	row := map[Attribute]AttributeValue{}
-	for attr, values := range d.Values {
-		row[attr] = values[idx]
+	for i, attr := range d.Attributes {
+		row[attr] = d.columns[i].value(d.storeRow(idx))
 	}
 	return row
 }
 
 func (d *DataSet) GetRowWithNames(idx int) map[string]AttributeValue {
 	row := map[string]AttributeValue{}
-	for attr, values := range d.Values {
-		row[attr.Name] = values[idx]
+	for i, attr := range d.Attributes {
+		row[attr.Name] = d.columns[i].value(d.storeRow(idx))
 	}
 	return row
 }
 
 func (d *DataSet) GetRowPlain(idx int) map[string]string {
 	row := map[string]string{}
-	for attr, values := range d.Values {
-		row[attr.Name] = attr.ValueToString(values[idx])
+	for i, attr := range d.Attributes {
+		row[attr.Name] = attr.ValueToString(d.columns[i].value(d.storeRow(idx)))
 	}
 	return row
 }
@@ -442,11 +542,15 @@ func (d *DataSet) AddRow(row map[Attribute]AttributeValue) {
 		}
 	}
 
-	for feature, value := range row {
-		if _, ok := d.Values[feature]; !ok {
+	for feature := range row {
+		if d.attributePosition(feature) < 0 {
 			panic(fmt.Sprintf("Feature %v does not exist in dataset", feature))
 		}
-		d.Values[feature] = append(d.Values[feature], value)
+	}
+
+	d.materialize()
+	for i, attr := range d.Attributes {
+		d.columns[i].append(row[attr])
 	}
 	d.Size++
 }
@@ -466,7 +570,7 @@ func (d *DataSet) ToCSV(w io.Writer) error {
 	for i := 0; i < d.Size; i++ {
 		record := make([]string, len(d.Attributes))
 		for j, feature := range d.Attributes {
-			record[j] = feature.ValueToString(d.Values[feature][i])
+			record[j] = feature.ValueToString(d.columns[j].value(d.storeRow(i)))
 		}
 
 		err = writer.Write(record)
@@ -479,8 +583,11 @@ func (d *DataSet) ToCSV(w io.Writer) error {
 	return writer.Error()
 }
 
+// splitCondition splits on a single attribute, at position index in the
+// attributes of the data set or forest it applies to.
 type splitCondition struct {
 	attribute Attribute
+	index     int
 	strVal    string
 	numVal    float64
 }
@@ -494,6 +601,25 @@ func (s *splitCondition) check(val AttributeValue) bool {
 	panic("Unknown feature type")
 }
 
+func (s *splitCondition) Matches(row RowValues) bool {
+	return s.check(row.Value(s.index))
+}
+
+func (s *splitCondition) Attributes() []Attribute {
+	return []Attribute{s.attribute}
+}
+
+func (s *splitCondition) Missing(row RowValues) bool {
+	return row.Value(s.index).Missing
+}
+
+func (s *splitCondition) describe(step *TraceStep, row RowValues) {
+	step.Attribute = s.attribute
+	step.Value = row.Value(s.index)
+	step.Threshold = s.numVal
+	step.Category = s.strVal
+}
+
 func (s *splitCondition) String(inverse bool) string {
 	if s.attribute.Type == AttributeTypeCategorical {
 		if inverse {
@@ -513,53 +639,206 @@ func (s *splitCondition) String(inverse bool) string {
 var ErrNotSplittable = errors.New("dataset not splittable")
 
 func (d *DataSet) Split(exclude map[Attribute]bool) (*splitCondition, *DataSet, *DataSet, error) {
+	return d.SplitRand(exclude, newRand())
+}
 
-	splittable := make([]Attribute, 0)
-	for _, attr := range d.Attributes {
+// SplitRand is like Split but draws from r, so the same source state always
+// produces the same split.
+func (d *DataSet) SplitRand(exclude map[Attribute]bool, r *rand.Rand) (*splitCondition, *DataSet, *DataSet, error) {
+	condition, err := d.randomSplitCondition(d.candidates(exclude), r, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	matched, notMatched := d.splitOn(condition)
+
+	return condition, matched, notMatched, nil
+}
+
+// candidates returns the positions of the attributes not in exclude.
+func (d *DataSet) candidates(exclude map[Attribute]bool) []int {
+	candidates := make([]int, 0, len(d.Attributes))
+	for i, attr := range d.Attributes {
 		if _, ok := exclude[attr]; !ok {
-			splittable = append(splittable, attr)
+			candidates = append(candidates, i)
 		}
 	}
+	return candidates
+}
 
-	if len(splittable) == 0 {
-		return nil, nil, nil, ErrNotSplittable
+// randomSplitCondition splits on a random attribute among those at positions
+// candidates. Categories are drawn from a random row, or uniformly from the
+// distinct values present if uniformCategories is set.
+func (d *DataSet) randomSplitCondition(candidates []int, r *rand.Rand, uniformCategories bool) (*splitCondition, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNotSplittable
 	}
+	return d.randomSplitConditionOn(candidates[r.Intn(len(candidates))], r, uniformCategories), nil
+}
 
-	splitAttr := splittable[rand.Intn(len(splittable))]
-	condition := &splitCondition{attribute: splitAttr}
+// randomSplitConditionOn splits on the attribute at position idx as
+// randomSplitCondition does.
+func (d *DataSet) randomSplitConditionOn(idx int, r *rand.Rand, uniformCategories bool) *splitCondition {
+	splitAttr := d.Attributes[idx]
+	condition := &splitCondition{attribute: splitAttr, index: idx}
 	if splitAttr.Type == AttributeTypeCategorical {
-		condition.strVal = d.Values[splitAttr][rand.Intn(len(d.Values[splitAttr]))].Str
+		if uniformCategories {
+			condition.strVal = d.uniformCategory(idx, r)
+		} else {
+			condition.strVal = d.randomCategory(idx, r)
+		}
 	} else if splitAttr.Type == AttributeTypeNumerical {
-		min := math.Inf(1)
-		max := math.Inf(-1)
-		for _, value := range d.Values[splitAttr] {
-			if value.Num < min {
-				min = value.Num
-			}
-			if value.Num > max {
-				max = value.Num
-			}
+		min, max := d.numericRange(idx)
+		condition.numVal = min + (r.Float64() * (max - min))
+	}
+
+	return condition
+}
+
+// randomCategorySet splits the categorical attribute at position idx on a
+// random partition of its distinct values into two non-empty sets. Unseen
+// categories follow the set holding fewer rows. It reports false if there are
+// fewer than two distinct values.
+func (d *DataSet) randomCategorySet(idx int, r *rand.Rand) (*setCondition, bool) {
+	categories, counts := d.categoryCounts(idx)
+	if len(categories) < 2 {
+		return nil, false
+	}
+
+	inLeft := make([]bool, len(categories))
+	numLeft := 0
+	for i := range inLeft {
+		if inLeft[i] = r.Intn(2) == 0; inLeft[i] {
+			numLeft++
 		}
-		condition.numVal = min + (rand.Float64() * (max - min))
+	}
+	if numLeft == 0 || numLeft == len(categories) {
+		i := r.Intn(len(categories))
+		inLeft[i] = !inLeft[i]
 	}
 
-	matched, notMatched := d.splitOn(condition)
+	var left, right []string
+	var leftRows, rightRows int
+	for i, category := range categories {
+		if inLeft[i] {
+			left = append(left, category)
+			leftRows += counts[i]
+		} else {
+			right = append(right, category)
+			rightRows += counts[i]
+		}
+	}
+	return newSetCondition(d.Attributes[idx], idx, left, right, leftRows < rightRows), true
+}
 
-	return condition, matched, notMatched, nil
+// randomCategory returns the value of the categorical attribute at position
+// idx in a random row, skipping rows where it is missing. If every value is
+// missing it returns an empty string.
+func (d *DataSet) randomCategory(idx int, r *rand.Rand) string {
+	col := d.columns[idx]
+	if row := d.storeRow(r.Intn(d.Size)); !col.isMissing(row) {
+		return col.dict.values[col.codes[row]]
+	}
+
+	present := make([]string, 0)
+	for i := 0; i < d.Size; i++ {
+		if row := d.storeRow(i); !col.isMissing(row) {
+			present = append(present, col.dict.values[col.codes[row]])
+		}
+	}
+	if len(present) == 0 {
+		return ""
+	}
+	return present[r.Intn(len(present))]
+}
+
+// uniformCategory returns one of the distinct values of the categorical
+// attribute at position idx, each equally likely. If every value is missing it
+// returns an empty string.
+func (d *DataSet) uniformCategory(idx int, r *rand.Rand) string {
+	categories, _ := d.categoryCounts(idx)
+	if len(categories) == 0 {
+		return ""
+	}
+	return categories[r.Intn(len(categories))]
 }
 
-func (d *DataSet) splitOn(condition *splitCondition) (*DataSet, *DataSet) {
-	matched := d.CopyNoValues()
-	notMatched := d.CopyNoValues()
+// categoryCounts returns the distinct values of the categorical attribute at
+// position idx in the order they first appear, and the number of rows holding
+// each. Missing values are not counted.
+func (d *DataSet) categoryCounts(idx int) ([]string, []int) {
+	col := d.columns[idx]
+	positions := map[uint32]int{}
+	var categories []string
+	var counts []int
+	for i := 0; i < d.Size; i++ {
+		row := d.storeRow(i)
+		if col.isMissing(row) {
+			continue
+		}
+		code := col.codes[row]
+		pos, ok := positions[code]
+		if !ok {
+			pos = len(categories)
+			positions[code] = pos
+			categories = append(categories, col.dict.values[code])
+			counts = append(counts, 0)
+		}
+		counts[pos]++
+	}
+	return categories, counts
+}
 
+// numericRange returns the smallest and largest values of the numerical
+// attribute at position idx, ignoring missing values. If every value is
+// missing it returns zero for both.
+func (d *DataSet) numericRange(idx int) (float64, float64) {
+	col := d.columns[idx]
+	min := math.Inf(1)
+	max := math.Inf(-1)
 	for i := 0; i < d.Size; i++ {
-		row := d.GetRow(i)
-		if condition.check(row[condition.attribute]) {
-			matched.AddRow(row)
+		row := d.storeRow(i)
+		if col.isMissing(row) {
+			continue
+		}
+		if value := col.num[row]; value < min {
+			min = value
+		}
+		if value := col.num[row]; value > max {
+			max = value
+		}
+	}
+	if min > max {
+		return 0, 0
+	}
+	return min, max
+}
+
+// splitOn partitions the data set into rows that match condition and rows
+// that do not. Rows missing a value the condition depends on follow the
+// majority of the other rows.
+func (d *DataSet) splitOn(condition Condition) (*DataSet, *DataSet) {
+	matched := make([]int, 0, d.Size)
+	notMatched := make([]int, 0, d.Size)
+	var missing []int
+
+	row := &dataSetRow{d: d}
+	for i := 0; i < d.Size; i++ {
+		row.row = i
+		if condition.Missing(row) {
+			missing = append(missing, d.storeRow(i))
+		} else if condition.Matches(row) {
+			matched = append(matched, d.storeRow(i))
 		} else {
-			notMatched.AddRow(row)
+			notMatched = append(notMatched, d.storeRow(i))
 		}
 	}
 
-	return matched, notMatched
+	if len(notMatched) > len(matched) {
+		notMatched = append(notMatched, missing...)
+	} else {
+		matched = append(matched, missing...)
+	}
+
+	return d.view(d.Attributes, d.columns, matched), d.view(d.Attributes, d.columns, notMatched)
 }
@@ -2,6 +2,7 @@ package goiforest
 
 import (
 	"encoding/csv"
+	"math/rand"
 	"reflect"
 	"strings"
 	"testing"
@@ -48,76 +49,123 @@ func TestDataSetFromCSV(t *testing.T) {
 		},
 	}
 
-	if !reflect.DeepEqual(ds.Values, expected) {
-		t.Errorf("Expected %v, got %v", expected, ds.Values)
+	if !reflect.DeepEqual(ds.Values(), expected) {
+		t.Errorf("Expected %v, got %v", expected, ds.Values())
 	}
 }
 
 func TestSplit(t *testing.T) {
-	ds := DataSet{
-		Attributes: []Attribute{
-			{Name: "Name", Type: AttributeTypeCategorical},
-			{Name: "Color", Type: AttributeTypeCategorical},
-		},
-		Values: map[Attribute][]AttributeValue{
-			{Name: "Name", Type: AttributeTypeCategorical}: {
-				{Str: "apple"},
-				{Str: "raspberry"},
-				{Str: "pear"},
-			},
-			{Name: "Color", Type: AttributeTypeCategorical}: {
-				{Str: "red"},
-				{Str: "red"},
-				{Str: "green"},
-			},
-		},
-		Size: 3,
+	name := Attribute{Name: "Name", Type: AttributeTypeCategorical}
+	color := Attribute{Name: "Color", Type: AttributeTypeCategorical}
+
+	ds := NewDataSet()
+	ds.AddAttribute(name)
+	ds.AddAttribute(color)
+	for _, fruit := range [][2]string{{"apple", "red"}, {"raspberry", "red"}, {"pear", "green"}} {
+		ds.AddRow(map[Attribute]AttributeValue{name: {Str: fruit[0]}, color: {Str: fruit[1]}})
 	}
 
 	actualLeft, actualRight := ds.splitOn(&splitCondition{
-		attribute: Attribute{Name: "Color", Type: AttributeTypeCategorical},
+		attribute: color,
+		index:     1,
 		strVal:    "red",
 	})
 
-	expectedLeft := &DataSet{
-		Attributes: []Attribute{
-			{Name: "Name", Type: AttributeTypeCategorical},
-			{Name: "Color", Type: AttributeTypeCategorical},
-		},
-		Values: map[Attribute][]AttributeValue{
-			{Name: "Name", Type: AttributeTypeCategorical}: {
-				{Str: "apple"},
-				{Str: "raspberry"},
-			},
-			{Name: "Color", Type: AttributeTypeCategorical}: {
-				{Str: "red"},
-				{Str: "red"},
-			},
-		},
-		Size: 2,
+	expectedLeft := map[Attribute][]AttributeValue{
+		name:  {{Str: "apple"}, {Str: "raspberry"}},
+		color: {{Str: "red"}, {Str: "red"}},
+	}
+
+	expectedRight := map[Attribute][]AttributeValue{
+		name:  {{Str: "pear"}},
+		color: {{Str: "green"}},
+	}
+
+	if actualLeft.Size != 2 || !reflect.DeepEqual(actualLeft.Values(), expectedLeft) {
+		t.Errorf("Expected %v, got %v", expectedLeft, actualLeft.Values())
+	}
+
+	if actualRight.Size != 1 || !reflect.DeepEqual(actualRight.Values(), expectedRight) {
+		t.Errorf("Expected %v, got %v", expectedRight, actualRight.Values())
+	}
+
+	if !reflect.DeepEqual(ds.Attributes, actualLeft.Attributes) {
+		t.Errorf("Expected attributes %v, got %v", ds.Attributes, actualLeft.Attributes)
+	}
+}
+
+func TestSplitRandSeeded(t *testing.T) {
+	ds := testDataSet(100)
+
+	a, aLeft, _, err := ds.SplitRand(nil, rand.New(rand.NewSource(3)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, bLeft, _, err := ds.SplitRand(nil, rand.New(rand.NewSource(3)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(a, b) || aLeft.Size != bLeft.Size {
+		t.Errorf("Expected identical splits for the same seed, got %v and %v", a, b)
 	}
 
-	expectedRight := &DataSet{
-		Attributes: []Attribute{
-			{Name: "Name", Type: AttributeTypeCategorical},
-			{Name: "Color", Type: AttributeTypeCategorical},
+	if _, left, right, err := ds.Split(nil); err != nil || left.Size+right.Size != ds.Size {
+		t.Errorf("Expected Split to partition all %d rows, got error %v", ds.Size, err)
+	}
+}
+
+func TestDataSetFromCSVMissingValues(t *testing.T) {
+	input := `Name,Cost
+		apple,0.5
+		NA,
+		pear,null`
+	attributes := map[string]AttributeType{
+		"Name": AttributeTypeCategorical,
+		"Cost": AttributeTypeNumerical,
+	}
+
+	if _, err := NewDataSetFromCSV(csv.NewReader(strings.NewReader(input)), attributes); err == nil {
+		t.Errorf("Expected error parsing missing values without missing tokens")
+	}
+
+	ds, err := NewDataSetFromCSVWithOptions(csv.NewReader(strings.NewReader(input)), attributes,
+		CSVOptions{MissingTokens: DefaultMissingTokens})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[Attribute][]AttributeValue{
+		{Name: "Name", Type: AttributeTypeCategorical}: {
+			{Str: "apple"},
+			{Missing: true},
+			{Str: "pear"},
 		},
-		Values: map[Attribute][]AttributeValue{
-			{Name: "Name", Type: AttributeTypeCategorical}: {
-				{Str: "pear"},
-			},
-			{Name: "Color", Type: AttributeTypeCategorical}: {
-				{Str: "green"},
-			},
+		{Name: "Cost", Type: AttributeTypeNumerical}: {
+			{Num: 0.5},
+			{Missing: true},
+			{Missing: true},
 		},
-		Size: 1,
 	}
 
-	if !reflect.DeepEqual(actualLeft, expectedLeft) {
-		t.Errorf("Expected %v, got %v", expectedLeft, actualLeft)
+	if !reflect.DeepEqual(ds.Values(), expected) {
+		t.Errorf("Expected %v, got %v", expected, ds.Values())
+	}
+}
+
+func TestSplitMissingFollowsMajority(t *testing.T) {
+	cost := Attribute{Name: "Cost", Type: AttributeTypeNumerical}
+	ds := NewDataSet()
+	ds.AddAttribute(cost)
+	for _, v := range []AttributeValue{{Num: 1}, {Num: 2}, {Num: 3}, {Missing: true}} {
+		ds.AddRow(map[Attribute]AttributeValue{cost: v})
 	}
 
-	if !reflect.DeepEqual(actualRight, expectedRight) {
-		t.Errorf("Expected %v, got %v", expectedRight, actualRight)
+	left, right := ds.splitOn(&splitCondition{attribute: cost, numVal: 2.5})
+
+	if left.Size != 1 || right.Size != 3 {
+		t.Errorf("Expected sizes 1 and 3, got %d and %d", left.Size, right.Size)
+	}
+	if !right.Value(2, cost).Missing {
+		t.Errorf("Expected missing value to follow the majority branch")
 	}
 }
@@ -0,0 +1,298 @@
+// Package evaluation measures how well anomaly scores separate labelled
+// anomalies from normal data.
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mikemherron/goiforest"
+)
+
+// ErrSingleClass is returned by metrics that need both anomalies and normal
+// rows when the labels contain only one of them.
+var ErrSingleClass = errors.New("labels contain a single class")
+
+// Evaluation holds the metrics computed by Evaluate.
+type Evaluation struct {
+	// Scores and Labels hold the score and label of each row, for computing
+	// further metrics.
+	Scores []float64
+	Labels []bool
+	ROCAUC float64
+	PRAUC  float64
+	// PrecisionAtK and RecallAtK are measured with k set to the number of
+	// labelled anomalies.
+	PrecisionAtK float64
+	RecallAtK    float64
+	// Confusion is measured at the forest's threshold.
+	Confusion ConfusionMatrix
+}
+
+func (e *Evaluation) String() string {
+	return fmt.Sprintf("ROC AUC: %.4f\nPR AUC: %.4f\nPrecision@%d: %.4f\nRecall@%d: %.4f\n%v",
+		e.ROCAUC, e.PRAUC, positives(e.Labels), e.PrecisionAtK, positives(e.Labels), e.RecallAtK, e.Confusion)
+}
+
+// Evaluate scores dataSet with forest and compares the scores with the labels
+// read from the attribute named label, where rows whose label is positive, as
+// described by Labels, are anomalies. The label attribute is not used for scoring, so it
+// need not be excluded from dataSet.
+func Evaluate(forest *goiforest.IsolationForest, dataSet *goiforest.DataSet, label, positive string) (*Evaluation, error) {
+	labels, err := Labels(dataSet, label, positive)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := forest.ScoreDataSet(dataSet, goiforest.BatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	eval := &Evaluation{
+		Scores:    result.Scores,
+		Labels:    labels,
+		Confusion: Confusion(result.Scores, labels, forest.Threshold()),
+	}
+	if eval.ROCAUC, err = ROCAUC(result.Scores, labels); err != nil {
+		return nil, err
+	}
+	if eval.PRAUC, err = PRAUC(result.Scores, labels); err != nil {
+		return nil, err
+	}
+	k := positives(labels)
+	eval.PrecisionAtK = PrecisionAtK(result.Scores, labels, k)
+	eval.RecallAtK = RecallAtK(result.Scores, labels, k)
+	return eval, nil
+}
+
+// Labels reads the attribute named label from each row of dataSet, reporting
+// true for rows whose value is positive. For a numerical attribute positive is
+// parsed and compared as a number, so "1" matches a value of 1. Missing values
+// of a numerical attribute are never positive.
+func Labels(dataSet *goiforest.DataSet, label, positive string) ([]bool, error) {
+	var attr goiforest.Attribute
+	found := false
+	for _, a := range dataSet.Attributes {
+		if a.Name == label {
+			attr, found = a, true
+			break
+		}
+	}
+	if !found {
+		return nil, &goiforest.MissingAttributeError{Attribute: label}
+	}
+
+	labels := make([]bool, dataSet.Size)
+	if attr.Type == goiforest.AttributeTypeNumerical {
+		want, err := goiforest.ParseAttributeValue(attr, strings.TrimSpace(positive))
+		if err != nil {
+			return nil, fmt.Errorf("invalid positive label: %w", err)
+		}
+		for i := range labels {
+			v := dataSet.Value(i, attr)
+			labels[i] = !v.Missing && v.Num == want.Num
+		}
+		return labels, nil
+	}
+
+	for i := range labels {
+		labels[i] = attr.ValueToString(dataSet.Value(i, attr)) == positive
+	}
+	return labels, nil
+}
+
+// ROCAUC returns the area under the receiver operating characteristic curve,
+// the probability that a random anomaly scores higher than a random normal
+// row, counting ties as half.
+func ROCAUC(scores []float64, labels []bool) (float64, error) {
+	if err := check(scores, labels); err != nil {
+		return 0, err
+	}
+
+	order := ranked(scores)
+	var positiveRanks float64
+	for start := 0; start < len(order); {
+		end := tieEnd(scores, order, start)
+		// Tied scores share the mean of their ranks, counting from 1 for
+		// the lowest score.
+		rank := float64(len(order)-end+1+len(order)-start) / 2
+		for _, i := range order[start:end] {
+			if labels[i] {
+				positiveRanks += rank
+			}
+		}
+		start = end
+	}
+
+	p := float64(positives(labels))
+	n := float64(len(labels)) - p
+	return (positiveRanks - p*(p+1)/2) / (p * n), nil
+}
+
+// PRAUC returns the area under the precision-recall curve, computed as the
+// average precision: the mean of the precision at each threshold weighted by
+// the increase in recall.
+func PRAUC(scores []float64, labels []bool) (float64, error) {
+	if err := check(scores, labels); err != nil {
+		return 0, err
+	}
+
+	order := ranked(scores)
+	total := float64(positives(labels))
+	var area, truePositives float64
+	for start := 0; start < len(order); {
+		end := tieEnd(scores, order, start)
+		found := 0.0
+		for _, i := range order[start:end] {
+			if labels[i] {
+				found++
+			}
+		}
+		truePositives += found
+		area += found / total * truePositives / float64(end)
+		start = end
+	}
+	return area, nil
+}
+
+// PrecisionAtK returns the share of anomalies among the k highest scoring
+// rows. Ties at the k-th score are broken by row order.
+func PrecisionAtK(scores []float64, labels []bool, k int) float64 {
+	k = min(k, len(scores))
+	if k <= 0 {
+		return 0
+	}
+	return float64(topPositives(scores, labels, k)) / float64(k)
+}
+
+// RecallAtK returns the share of all anomalies found among the k highest
+// scoring rows. Ties at the k-th score are broken by row order.
+func RecallAtK(scores []float64, labels []bool, k int) float64 {
+	total := positives(labels)
+	if total == 0 || k <= 0 {
+		return 0
+	}
+	return float64(topPositives(scores, labels, min(k, len(scores)))) / float64(total)
+}
+
+// ConfusionMatrix counts rows by label and by whether they score above a
+// threshold.
+type ConfusionMatrix struct {
+	TruePositives  int
+	FalsePositives int
+	TrueNegatives  int
+	FalseNegatives int
+}
+
+// Confusion returns the confusion matrix of predicting rows scoring above
+// threshold as anomalies, as IsolationForest.Predict does.
+func Confusion(scores []float64, labels []bool, threshold float64) ConfusionMatrix {
+	var m ConfusionMatrix
+	for i, score := range scores {
+		switch predicted := score > threshold; {
+		case predicted && labels[i]:
+			m.TruePositives++
+		case predicted:
+			m.FalsePositives++
+		case labels[i]:
+			m.FalseNegatives++
+		default:
+			m.TrueNegatives++
+		}
+	}
+	return m
+}
+
+// Precision returns the share of predicted anomalies that are anomalies, or
+// zero if none were predicted.
+func (m ConfusionMatrix) Precision() float64 {
+	return ratio(m.TruePositives, m.TruePositives+m.FalsePositives)
+}
+
+// Recall returns the share of anomalies that were predicted, or zero if there
+// are none.
+func (m ConfusionMatrix) Recall() float64 {
+	return ratio(m.TruePositives, m.TruePositives+m.FalseNegatives)
+}
+
+// F1 returns the harmonic mean of precision and recall.
+func (m ConfusionMatrix) F1() float64 {
+	return ratio(2*m.TruePositives, 2*m.TruePositives+m.FalsePositives+m.FalseNegatives)
+}
+
+// Accuracy returns the share of rows predicted correctly.
+func (m ConfusionMatrix) Accuracy() float64 {
+	return ratio(m.TruePositives+m.TrueNegatives,
+		m.TruePositives+m.TrueNegatives+m.FalsePositives+m.FalseNegatives)
+}
+
+func (m ConfusionMatrix) String() string {
+	return fmt.Sprintf("%-8s %18s %18s\n%-8s %18d %18d\n%-8s %18d %18d\nPrecision: %.4f Recall: %.4f F1: %.4f",
+		"", "Predicted anomaly", "Predicted normal",
+		"Anomaly", m.TruePositives, m.FalseNegatives,
+		"Normal", m.FalsePositives, m.TrueNegatives,
+		m.Precision(), m.Recall(), m.F1())
+}
+
+func check(scores []float64, labels []bool) error {
+	if len(scores) != len(labels) {
+		return fmt.Errorf("%d scores but %d labels", len(scores), len(labels))
+	}
+	if p := positives(labels); p == 0 || p == len(labels) {
+		return ErrSingleClass
+	}
+	return nil
+}
+
+func positives(labels []bool) int {
+	count := 0
+	for _, l := range labels {
+		if l {
+			count++
+		}
+	}
+	return count
+}
+
+// ranked returns the indices of scores from highest to lowest score, keeping
+// row order among ties.
+func ranked(scores []float64) []int {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	return order
+}
+
+// tieEnd returns the end of the run of rows in order from start that share the
+// score of order[start].
+func tieEnd(scores []float64, order []int, start int) int {
+	end := start + 1
+	for end < len(order) && scores[order[end]] == scores[order[start]] {
+		end++
+	}
+	return end
+}
+
+func topPositives(scores []float64, labels []bool, k int) int {
+	count := 0
+	for _, i := range ranked(scores)[:k] {
+		if labels[i] {
+			count++
+		}
+	}
+	return count
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
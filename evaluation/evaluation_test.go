@@ -0,0 +1,166 @@
+package evaluation
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/mikemherron/goiforest"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestROCAUC(t *testing.T) {
+	for _, test := range []struct {
+		scores   []float64
+		labels   []bool
+		expected float64
+	}{
+		{[]float64{0.9, 0.8, 0.3, 0.1}, []bool{true, true, false, false}, 1},
+		{[]float64{0.9, 0.8, 0.3, 0.1}, []bool{false, false, true, true}, 0},
+		{[]float64{0.9, 0.8, 0.3, 0.1}, []bool{true, false, true, false}, 0.75},
+		{[]float64{0.5, 0.5, 0.5, 0.5}, []bool{true, false, true, false}, 0.5},
+		{[]float64{0.9, 0.5, 0.5, 0.1}, []bool{true, true, false, false}, 0.875},
+	} {
+		actual, err := ROCAUC(test.scores, test.labels)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !almostEqual(actual, test.expected) {
+			t.Errorf("%v %v: expected %f, got %f", test.scores, test.labels, test.expected, actual)
+		}
+	}
+
+	if _, err := ROCAUC([]float64{0.1, 0.2}, []bool{true, true}); !errors.Is(err, ErrSingleClass) {
+		t.Errorf("Expected ErrSingleClass, got %v", err)
+	}
+	if _, err := ROCAUC([]float64{0.1}, []bool{true, false}); err == nil {
+		t.Errorf("Expected error for mismatched lengths")
+	}
+}
+
+func TestPRAUC(t *testing.T) {
+	scores := []float64{0.9, 0.8, 0.7, 0.6, 0.5}
+	labels := []bool{true, false, true, false, false}
+	// Precision is 1 at recall 0.5 and 2/3 at recall 1.
+	expected := 0.5*1 + 0.5*2.0/3
+	actual, err := PRAUC(scores, labels)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !almostEqual(actual, expected) {
+		t.Errorf("Expected %f, got %f", expected, actual)
+	}
+}
+
+func TestAtK(t *testing.T) {
+	scores := []float64{0.2, 0.9, 0.4, 0.8, 0.1}
+	labels := []bool{true, true, false, false, false}
+
+	if p := PrecisionAtK(scores, labels, 2); !almostEqual(p, 0.5) {
+		t.Errorf("Expected precision 0.5, got %f", p)
+	}
+	if r := RecallAtK(scores, labels, 2); !almostEqual(r, 0.5) {
+		t.Errorf("Expected recall 0.5, got %f", r)
+	}
+	if r := RecallAtK(scores, labels, 10); !almostEqual(r, 1) {
+		t.Errorf("Expected recall 1, got %f", r)
+	}
+}
+
+func TestConfusion(t *testing.T) {
+	scores := []float64{0.9, 0.7, 0.6, 0.4, 0.2}
+	labels := []bool{true, false, true, false, false}
+
+	m := Confusion(scores, labels, 0.6)
+	expected := ConfusionMatrix{TruePositives: 1, FalsePositives: 1, TrueNegatives: 2, FalseNegatives: 1}
+	if m != expected {
+		t.Errorf("Expected %+v, got %+v", expected, m)
+	}
+	if !almostEqual(m.Precision(), 0.5) || !almostEqual(m.Recall(), 0.5) ||
+		!almostEqual(m.F1(), 0.5) || !almostEqual(m.Accuracy(), 0.6) {
+		t.Errorf("Unexpected metrics %f %f %f %f", m.Precision(), m.Recall(), m.F1(), m.Accuracy())
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	x := goiforest.Attribute{Name: "X", Type: goiforest.AttributeTypeNumerical}
+	class := goiforest.Attribute{Name: "Class", Type: goiforest.AttributeTypeCategorical}
+	flag := goiforest.Attribute{Name: "Flag", Type: goiforest.AttributeTypeNumerical}
+	ds := goiforest.NewDataSet()
+	ds.AddAttribute(x)
+	ds.AddAttribute(class)
+	ds.AddAttribute(flag)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		value, label := r.NormFloat64(), "0"
+		if i%50 == 0 {
+			value, label = 8+r.NormFloat64(), "1"
+		}
+		ds.AddRow(map[goiforest.Attribute]goiforest.AttributeValue{
+			x: {Num: value}, class: {Str: label}, flag: goiforest.NewAttributeValue(flag, label),
+		})
+	}
+
+	training, err := ds.Excluding("Class", "Flag")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	forest, err := goiforest.BuildForestWithConfig(training, goiforest.ForestConfig{
+		NumTrees: 50, SampleSize: 128, Seed: 61, Contamination: 0.02,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	eval, err := Evaluate(forest, ds, "Class", "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if eval.ROCAUC < 0.99 || eval.PRAUC < 0.9 || eval.RecallAtK < 0.9 {
+		t.Errorf("Expected anomalies to be separated, got\n%v", eval)
+	}
+	if eval.Confusion.TruePositives+eval.Confusion.FalseNegatives != 10 {
+		t.Errorf("Expected 10 labelled anomalies, got %+v", eval.Confusion)
+	}
+
+	numerical, err := Evaluate(forest, ds, "Flag", "1")
+	if err != nil {
+		t.Fatalf("Unexpected error evaluating a numerical label: %v", err)
+	}
+	if numerical.ROCAUC != eval.ROCAUC {
+		t.Errorf("Expected ROC AUC %f with a numerical label, got %f", eval.ROCAUC, numerical.ROCAUC)
+	}
+
+	if _, err := Evaluate(forest, ds, "Label", "1"); err == nil {
+		t.Errorf("Expected error for unknown label attribute")
+	}
+}
+
+func TestLabelsNumerical(t *testing.T) {
+	class := goiforest.Attribute{Name: "Class", Type: goiforest.AttributeTypeNumerical}
+	ds := goiforest.NewDataSet()
+	ds.AddAttribute(class)
+	for _, v := range []goiforest.AttributeValue{{Num: 0}, {Num: 1}, {Missing: true}, {Num: 1}} {
+		ds.AddRow(map[goiforest.Attribute]goiforest.AttributeValue{class: v})
+	}
+
+	for _, positive := range []string{"1", "1.0", " 1"} {
+		labels, err := Labels(ds, "Class", positive)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := []bool{false, true, false, true}; !reflect.DeepEqual(labels, expected) {
+			t.Errorf("Expected labels %v for positive %q, got %v", expected, positive, labels)
+		}
+	}
+
+	if _, err := Labels(ds, "Class", "yes"); err == nil {
+		t.Errorf("Expected error for a non-numerical positive label")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/mikemherron/goiforest"
+	"github.com/mikemherron/goiforest/evaluation"
 )
 
 // This example demonstrates how to use the Isolation Forest to score a dataset
@@ -30,37 +31,13 @@ func main() {
 		panic(err)
 	}
 
-	dataSet, err := goiforest.NewDataSetFromCSV(csv.NewReader(file),
-		map[string]goiforest.AttributeType{
-			"V1":    goiforest.AttributeTypeNumerical,
-			"V2":    goiforest.AttributeTypeNumerical,
-			"V3":    goiforest.AttributeTypeNumerical,
-			"V4":    goiforest.AttributeTypeNumerical,
-			"V5":    goiforest.AttributeTypeNumerical,
-			"V6":    goiforest.AttributeTypeNumerical,
-			"V7":    goiforest.AttributeTypeNumerical,
-			"V8":    goiforest.AttributeTypeNumerical,
-			"V9":    goiforest.AttributeTypeNumerical,
-			"V10":   goiforest.AttributeTypeNumerical,
-			"V11":   goiforest.AttributeTypeNumerical,
-			"V12":   goiforest.AttributeTypeNumerical,
-			"V13":   goiforest.AttributeTypeNumerical,
-			"V14":   goiforest.AttributeTypeNumerical,
-			"V15":   goiforest.AttributeTypeNumerical,
-			"V16":   goiforest.AttributeTypeNumerical,
-			"V17":   goiforest.AttributeTypeNumerical,
-			"V18":   goiforest.AttributeTypeNumerical,
-			"V19":   goiforest.AttributeTypeNumerical,
-			"V20":   goiforest.AttributeTypeNumerical,
-			"V21":   goiforest.AttributeTypeNumerical,
-			"V22":   goiforest.AttributeTypeNumerical,
-			"V23":   goiforest.AttributeTypeNumerical,
-			"V24":   goiforest.AttributeTypeNumerical,
-			"V25":   goiforest.AttributeTypeNumerical,
-			"V26":   goiforest.AttributeTypeNumerical,
-			"V27":   goiforest.AttributeTypeNumerical,
-			"V28":   goiforest.AttributeTypeNumerical,
-			"Class": goiforest.AttributeTypeCategorical,
+	// Infer the schema from the file, reading the label as categorical
+	dataSet, _, err := goiforest.NewDataSetFromCSVInferred(csv.NewReader(file),
+		goiforest.InferOptions{
+			Override: map[string]goiforest.AttributeType{
+				"Class": goiforest.AttributeTypeCategorical,
+			},
+			Exclude: []string{"Time", "Amount"},
 		},
 	)
 
@@ -95,11 +72,21 @@ func main() {
 	}
 
 	// Score the testing set
+	result, err := forest.ScoreDataSet(testingSet, goiforest.BatchOptions{PathLengths: true})
+	if err != nil {
+		panic(err)
+	}
+
 	for i := 0; i < testingSet.Size; i++ {
-		record := testingSet.GetRowPlain(i)
-		result := forest.Score(record)
-		fmt.Printf("Label: %s, Score: %f Avg Path: %f\n", record["Class"],
-			result.Score, result.AveragePathLength)
+		record := testingSet.GetRowWithNames(i)
+		fmt.Printf("Label: %s, Score: %f Avg Path: %f\n", record["Class"].Str,
+			result.Scores[i], result.AveragePathLengths[i])
 	}
 
+	// Evaluate the forest against the labels of the whole data set
+	eval, err := evaluation.Evaluate(forest, dataSet, "Class", "1")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(eval)
 }
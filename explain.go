@@ -0,0 +1,132 @@
+package goiforest
+
+import (
+	"math"
+	"sort"
+)
+
+// AttributeContribution is the share of a score attributed to an attribute.
+type AttributeContribution struct {
+	Attribute    Attribute
+	Contribution float64
+}
+
+// Explanation breaks a score down by attribute. Contributions sum to one
+// unless the data point reached a leaf at the root of every tree, in which
+// case no attribute contributed. Ranked holds the same contributions sorted
+// from largest to smallest.
+type Explanation struct {
+	ScoreResult
+	Contributions map[Attribute]float64
+	Ranked        []AttributeContribution
+}
+
+// Explain scores dataPoint and attributes the score to the forest's
+// attributes. Every split on the path of the data point through every tree
+// credits the attributes it splits on with the share of the node's training
+// rows it separated from the point, so splits that isolate the point quickly
+// count the most and splits that leave it among most rows count for little.
+// Hyperplane splits share their credit between attributes in proportion to
+// the magnitude of their normal components. Splits that followed both
+// branches because of a missing value credit nothing, as they did not help
+// isolate the point. If the point is scored 1 under the UnseenAnomalous
+// policy, the attributes with unseen values share all the credit.
+//
+// TreeTraces are only included in the result if requested in opts.
+func (f *IsolationForest) Explain(dataPoint map[string]string, opts ScoreOptions) (Explanation, error) {
+	traced := opts
+	traced.Trace = true
+	result, err := f.ScoreWithOptions(dataPoint, traced)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	contributions := f.contributions(result.TreeTraces)
+	if len(result.Unseen) > 0 && f.config.UnseenCategories == UnseenAnomalous {
+		for _, attr := range result.Unseen {
+			contributions[attr] = 1 / float64(len(result.Unseen))
+		}
+	}
+
+	if !opts.Trace {
+		result.TreeTraces = nil
+	}
+
+	return Explanation{
+		ScoreResult:   result,
+		Contributions: contributions,
+		Ranked:        rankContributions(contributions),
+	}, nil
+}
+
+// contributions attributes the path lengths recorded in traces to the forest's
+// attributes as described by Explain.
+func (f *IsolationForest) contributions(traces []TreeTrace) map[Attribute]float64 {
+	contributions := make(map[Attribute]float64, len(f.attributes))
+	f.setContributions(contributions, traces)
+	return contributions
+}
+
+// setContributions is like contributions but overwrites the entries of
+// contributions, so a map can be reused between data points.
+func (f *IsolationForest) setContributions(contributions map[Attribute]float64, traces []TreeTrace) {
+	for _, attr := range f.attributes {
+		contributions[attr] = 0
+	}
+
+	var total float64
+	for _, trace := range traces {
+		for _, step := range trace.Steps {
+			if step.Branch == BranchBoth {
+				continue
+			}
+			credit := step.Weight * (1 - float64(step.BranchSize)/float64(step.Size))
+			total += credit
+			creditStep(contributions, step, credit)
+		}
+	}
+
+	if total > 0 {
+		for attr := range contributions {
+			contributions[attr] /= total
+		}
+	}
+}
+
+func creditStep(contributions map[Attribute]float64, step TraceStep, credit float64) {
+	if step.Normal == nil && len(step.Attributes) > 1 {
+		// Other conditions on several attributes credit each equally.
+		for _, attr := range step.Attributes {
+			contributions[attr] += credit / float64(len(step.Attributes))
+		}
+		return
+	} else if step.Normal == nil {
+		contributions[step.Attribute] += credit
+		return
+	}
+
+	var norm float64
+	for _, n := range step.Normal {
+		norm += math.Abs(n)
+	}
+	if norm == 0 {
+		return
+	}
+	for i, attr := range step.Attributes {
+		contributions[attr] += credit * math.Abs(step.Normal[i]) / norm
+	}
+}
+
+func rankContributions(contributions map[Attribute]float64) []AttributeContribution {
+	ranked := make([]AttributeContribution, 0, len(contributions))
+	for attr, c := range contributions {
+		ranked = append(ranked, AttributeContribution{Attribute: attr, Contribution: c})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Contribution != ranked[j].Contribution {
+			return ranked[i].Contribution > ranked[j].Contribution
+		}
+		return ranked[i].Attribute.Name < ranked[j].Attribute.Name
+	})
+	return ranked
+}
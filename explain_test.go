@@ -0,0 +1,41 @@
+package goiforest
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	ds := testDataSet(1000)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 100, SampleSize: 256, Seed: 23})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	explanation, err := forest.Explain(map[string]string{"X": "8", "Y": "0", "Color": "red"}, ScoreOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if explanation.TreeTraces != nil {
+		t.Errorf("Expected no traces unless requested")
+	}
+
+	if explanation.Ranked[0].Attribute.Name != "X" {
+		t.Errorf("Expected X to contribute most, got %v", explanation.Ranked)
+	}
+
+	var total float64
+	for _, c := range explanation.Contributions {
+		total += c
+	}
+	if math.Abs(total-1) > 1e-9 {
+		t.Errorf("Expected contributions to sum to 1, got %f", total)
+	}
+
+	for i := 1; i < len(explanation.Ranked); i++ {
+		if explanation.Ranked[i].Contribution > explanation.Ranked[i-1].Contribution {
+			t.Errorf("Expected contributions to be sorted, got %v", explanation.Ranked)
+		}
+	}
+}
@@ -4,90 +4,374 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
+	"runtime"
 	"strconv"
+	"sync"
 )
 
 type IsolationForest struct {
 	Trees           []*IsolationTree
-	attributes      map[string]Attribute
+	attributes      []Attribute
 	expectedAverage float64
+	threshold       float64
+	config          ForestConfig
+	// categories holds the values of each categorical attribute seen in
+	// training by position, or is nil if they are not known.
+	categories []map[string]bool
+}
+
+// Config returns the configuration the forest was built with.
+func (f *IsolationForest) Config() ForestConfig {
+	return f.config
 }
 
 type ScoreResult struct {
 	Score             float64
 	Attributes        map[Attribute]AttributeValue
 	AveragePathLength float64
-	TreeTraces        [][]string
+	// Unseen lists the categorical attributes whose values were not seen in
+	// training, which are scored according to the forest's
+	// UnseenCategoryPolicy.
+	Unseen []Attribute
+	// TreeTraces holds the path taken through each tree. It is only populated
+	// when requested with ScoreOptions.Trace, and is nil for data points
+	// scored 1 under the UnseenAnomalous policy, which take no path.
+	TreeTraces []TreeTrace
 }
 
+// ScoreOptions controls ScoreWithOptions. The zero value produces a score
+// without traces and treats no value as missing.
+type ScoreOptions struct {
+	Trace bool
+	// MissingTokens lists values, compared after trimming white space, that
+	// are treated as missing rather than parsed.
+	MissingTokens []string
+}
+
+// Score is like TryScore but panics if the data point is invalid.
 func (f *IsolationForest) Score(dataPoint map[string]string) ScoreResult {
-	score := 0.0
+	result, err := f.TryScore(dataPoint)
+	if err != nil {
+		panic(err.Error())
+	}
+	return result
+}
+
+// TryScore scores a data point given as attribute names mapped to unparsed
+// values. It returns a *MissingAttributeError if an attribute of the forest is
+// absent, or the error from ParseAttributeValue if a value is invalid.
+func (f *IsolationForest) TryScore(dataPoint map[string]string) (ScoreResult, error) {
+	return f.ScoreWithOptions(dataPoint, ScoreOptions{})
+}
 
+// ScoreWithOptions is like TryScore but allows requesting traces.
+func (f *IsolationForest) ScoreWithOptions(dataPoint map[string]string, opts ScoreOptions) (ScoreResult, error) {
+	values := make(attributeValues, len(f.attributes))
 	dataPointAttributes := make(map[Attribute]AttributeValue)
-	for _, f := range f.attributes {
+	for i, f := range f.attributes {
 		val, exists := dataPoint[f.Name]
 		if !exists {
-			panic(fmt.Sprintf("Attribute %s not found on %v", f.Name, dataPoint))
+			return ScoreResult{}, &MissingAttributeError{Attribute: f.Name}
+		}
+		if isMissingToken(val, opts.MissingTokens) {
+			values[i] = AttributeValue{Missing: true}
+		} else {
+			attrVal, err := ParseAttributeValue(f, val)
+			if err != nil {
+				return ScoreResult{}, err
+			}
+			values[i] = attrVal
 		}
-		dataPointAttributes[f] = NewAttributeValue(f, val)
+		dataPointAttributes[f] = values[i]
 	}
 
-	traces := make([][]string, len(f.Trees))
-	var pathLengthTotal float64
-	for i, tree := range f.Trees {
-		n, trace := tree.traverse(dataPointAttributes)
-		traces[i] = trace
-		pathLengthTotal += n
+	unseen := f.unseenValues(values, nil)
+	if len(unseen) > 0 && f.config.UnseenCategories == UnseenError {
+		return ScoreResult{}, f.unseenError(values, unseen)
+	}
+
+	var traces []TreeTrace
+	if opts.Trace && f.traversed(unseen) {
+		traces = make([]TreeTrace, len(f.Trees))
 	}
 
-	avgPathLength := float64(pathLengthTotal) / float64(len(f.Trees))
-	score = math.Pow(2, (-avgPathLength / f.expectedAverage))
-	return ScoreResult{
-		Score:             score,
+	avgPathLength := f.policyPathLength(values, unseen, traces)
+	result := ScoreResult{
+		Score:             f.score(avgPathLength),
 		Attributes:        dataPointAttributes,
 		AveragePathLength: avgPathLength,
 		TreeTraces:        traces,
 	}
+	for _, i := range unseen {
+		result.Unseen = append(result.Unseen, f.attributes[i])
+	}
+	return result, nil
+}
+
+// averagePathLength returns the mean path length of row across the forest's
+// trees. If traces is not nil, the path through tree i is recorded in
+// traces[i]. If router is not nil, it chooses the branches taken by unseen
+// values.
+func (f *IsolationForest) averagePathLength(row RowValues, router *unseenRouter, traces []TreeTrace) float64 {
+	var pathLengthTotal float64
+	for i, tree := range f.Trees {
+		var trace *TreeTrace
+		if traces != nil {
+			trace = &traces[i]
+		}
+		pathLengthTotal += tree.traverse(row, router, trace)
+	}
+	return pathLengthTotal / float64(len(f.Trees))
+}
+
+func (f *IsolationForest) score(avgPathLength float64) float64 {
+	return math.Pow(2, (-avgPathLength / f.expectedAverage))
+}
+
+// BatchOptions controls ScoreDataSet. The zero value scores rows using
+// GOMAXPROCS workers and returns scores only.
+type BatchOptions struct {
+	Workers     int
+	PathLengths bool
+	Traces      bool
+}
+
+// BatchResult holds one entry per data set row in each populated slice.
+// AveragePathLengths and TreeTraces are only set when requested in
+// BatchOptions. As in ScoreResult, the traces of rows scored 1 under the
+// UnseenAnomalous policy are nil.
+type BatchResult struct {
+	Scores             []float64
+	AveragePathLengths []float64
+	TreeTraces         [][]TreeTrace
+}
+
+// batchChunkSize is the number of rows a worker scores before taking more work.
+const batchChunkSize = 256
+
+// ScoreDataSet scores every row of dataSet, which must contain all of the
+// forest's attributes. Values are read directly from the data set rather than
+// converted through strings as Score requires. If the forest's unseen category
+// policy is UnseenError, no rows are scored if any has an unseen value, and
+// the error gives the first such row.
+func (f *IsolationForest) ScoreDataSet(dataSet *DataSet, opts BatchOptions) (*BatchResult, error) {
+	if opts.Workers < 0 {
+		return nil, fmt.Errorf("workers must not be negative, got %d", opts.Workers)
+	}
+	if opts.Workers == 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+
+	columns, err := f.columns(dataSet)
+	if err != nil {
+		return nil, err
+	}
+	if f.config.UnseenCategories == UnseenError {
+		if err := f.checkUnseen(dataSet, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BatchResult{Scores: make([]float64, dataSet.Size)}
+	if opts.PathLengths {
+		result.AveragePathLengths = make([]float64, dataSet.Size)
+	}
+	if opts.Traces {
+		result.TreeTraces = make([][]TreeTrace, dataSet.Size)
+	}
+
+	f.scoreChunks(dataSet, columns, opts.Workers, func() func(int, attributeValues, []int) {
+		return func(i int, row attributeValues, unseen []int) {
+			f.scoreBatchRow(row, unseen, i, result, opts.Traces)
+		}
+	})
+
+	return result, nil
+}
+
+// scoreChunks reads the rows of dataSet from columns in chunks of
+// batchChunkSize, spread across workers goroutines. Each goroutine calls
+// newWorker once and passes the function it returns the index, values and
+// unseen values of every row it reads. row and unseen are reused between rows.
+func (f *IsolationForest) scoreChunks(dataSet *DataSet, columns []*column, workers int,
+	newWorker func() func(i int, row attributeValues, unseen []int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			score := newWorker()
+			row := make(attributeValues, len(f.attributes))
+			var unseen []int
+			for start := range jobs {
+				end := start + batchChunkSize
+				if end > dataSet.Size {
+					end = dataSet.Size
+				}
+				for i := start; i < end; i++ {
+					for j, col := range columns {
+						row[j] = col.value(dataSet.storeRow(i))
+					}
+					unseen = f.unseenValues(row, unseen[:0])
+					score(i, row, unseen)
+				}
+			}
+		}()
+	}
+
+	for start := 0; start < dataSet.Size; start += batchChunkSize {
+		jobs <- start
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// columns returns the columns of dataSet holding the forest's attributes, in
+// the forest's order.
+func (f *IsolationForest) columns(dataSet *DataSet) ([]*column, error) {
+	columns := make([]*column, len(f.attributes))
+	for i, attr := range f.attributes {
+		pos := dataSet.attributePosition(attr)
+		if pos < 0 {
+			return nil, &MissingAttributeError{Attribute: attr.Name}
+		}
+		columns[i] = dataSet.columns[pos]
+	}
+	return columns, nil
+}
+
+// checkUnseen returns an error for the first row of dataSet with an unseen
+// value, reading the forest's attributes from columns.
+func (f *IsolationForest) checkUnseen(dataSet *DataSet, columns []*column) error {
+	row := make(attributeValues, len(f.attributes))
+	var unseen []int
+	for i := 0; i < dataSet.Size; i++ {
+		for j, col := range columns {
+			row[j] = col.value(dataSet.storeRow(i))
+		}
+		if unseen = f.unseenValues(row, unseen[:0]); len(unseen) > 0 {
+			return fmt.Errorf("row %d: %w", i, f.unseenError(row, unseen))
+		}
+	}
+	return nil
+}
+
+func (f *IsolationForest) scoreBatchRow(row attributeValues, unseen []int, i int, result *BatchResult, traced bool) {
+	var traces []TreeTrace
+	if traced && f.traversed(unseen) {
+		traces = make([]TreeTrace, len(f.Trees))
+	}
+
+	avgPathLength := f.policyPathLength(row, unseen, traces)
+	result.Scores[i] = f.score(avgPathLength)
+	if result.AveragePathLengths != nil {
+		result.AveragePathLengths[i] = avgPathLength
+	}
+	if traced {
+		result.TreeTraces[i] = traces
+	}
 }
 
 type IsolationTree struct {
 	Root *IsolationTreeNode
 }
 
+// newIsolationTree wraps root in a tree, numbering its nodes in pre-order so
+// they can be identified in traces.
+func newIsolationTree(root *IsolationTreeNode) *IsolationTree {
+	root.number(0)
+	return &IsolationTree{Root: root}
+}
+
 func (t *IsolationTree) String() string {
 	return t.Root.String(0)
 }
 
-func (t *IsolationTree) traverse(dataPoint map[Attribute]AttributeValue) (float64, []string) {
-	var pathLength float64 = 0.0
-	var traces []string
-	node := t.Root
-	for !node.isLeaf {
-		att := node.split.attribute
-		val := dataPoint[att]
-		if node.split.check(val) {
-			traces = append(traces, fmt.Sprintf("%s (%s)", node.split.String(false), att.ValueToString(val)))
-			node = node.left
-		} else {
-			traces = append(traces, fmt.Sprintf("%s (%s)", node.split.String(true), att.ValueToString(val)))
-			node = node.right
-		}
-		pathLength++
+// traverse returns the path length of dataPoint through the tree. If trace is
+// not nil, the path taken is recorded in it. If router is not nil, it chooses
+// the branches taken by unseen values.
+func (t *IsolationTree) traverse(dataPoint RowValues, router *unseenRouter, trace *TreeTrace) float64 {
+	pathLength := t.Root.pathLength(dataPoint, 0, 1, router, trace)
+	if trace != nil {
+		trace.PathLength = pathLength
 	}
-
-	traces = append(traces, fmt.Sprintf("Hit root, path length %f, remaining size: %d\n", pathLength, node.remainingSize))
-
-	return pathLength + avgPathLen(node.remainingSize), traces
+	return pathLength
 }
 
 type IsolationTreeNode struct {
+	id            int
 	left          *IsolationTreeNode
 	right         *IsolationTreeNode
-	split         *splitCondition
+	split         Condition
 	remainingSize int
 	isLeaf        bool
 }
 
+// pathLength returns the path length of dataPoint from n, which is at depth
+// in its tree. If the split at n depends on a value missing from dataPoint,
+// both children are followed and their path lengths weighted by the number of
+// training rows that went each way. weight is the share of the final path
+// length contributed by n, used only for tracing.
+func (n *IsolationTreeNode) pathLength(dataPoint RowValues, depth float64, weight float64, router *unseenRouter, trace *TreeTrace) float64 {
+	for !n.isLeaf {
+		if n.split.Missing(dataPoint) {
+			if trace != nil {
+				trace.addStep(n, dataPoint, depth, weight, BranchBoth)
+			}
+			leftWeight := float64(n.left.remainingSize) / float64(n.remainingSize)
+			return leftWeight*n.left.pathLength(dataPoint, depth+1, weight*leftWeight, router, trace) +
+				(1-leftWeight)*n.right.pathLength(dataPoint, depth+1, weight*(1-leftWeight), router, trace)
+		}
+
+		branch := BranchRight
+		if router != nil && router.routes(n.split) {
+			if router.left(n) {
+				branch = BranchLeft
+			}
+		} else if n.split.Matches(dataPoint) {
+			branch = BranchLeft
+		}
+		if trace != nil {
+			trace.addStep(n, dataPoint, depth, weight, branch)
+		}
+		if branch == BranchLeft {
+			n = n.left
+		} else {
+			n = n.right
+		}
+		depth++
+	}
+
+	if trace != nil {
+		trace.Leaves = append(trace.Leaves, TraceLeaf{ID: n.id, Size: n.remainingSize, Weight: weight})
+	}
+	return depth + avgPathLen(n.remainingSize)
+}
+
+// walk calls fn for n and each of its descendants in pre-order, where depth is
+// the depth of n.
+func (n *IsolationTreeNode) walk(depth int, fn func(node *IsolationTreeNode, depth int)) {
+	fn(n, depth)
+	if !n.isLeaf {
+		n.left.walk(depth+1, fn)
+		n.right.walk(depth+1, fn)
+	}
+}
+
+// number assigns pre-order ids to n and its descendants starting at id and
+// returns the next unused id.
+func (n *IsolationTreeNode) number(id int) int {
+	n.id = id
+	id++
+	if !n.isLeaf {
+		id = n.left.number(id)
+		id = n.right.number(id)
+	}
+	return id
+}
+
 func (n *IsolationTreeNode) String(depth int) string {
 	prefix := fmt.Sprintf("%"+strconv.Itoa(depth)+"s", "")
 	var s string
@@ -106,50 +390,297 @@ func (n *IsolationTreeNode) String(depth int) string {
 const NumTrees = 100
 const SampleSize = 256
 
+// ForestConfig controls how BuildForestWithConfig constructs a forest.
+type ForestConfig struct {
+	// NumTrees is the number of trees in the forest.
+	NumTrees int
+	// SampleSize is the number of rows sampled to build each tree.
+	SampleSize int
+	// MaxDepth limits the height of each tree. If zero, it is derived from
+	// SampleSize as ceil(log2(SampleSize)).
+	MaxDepth int
+	// Attributes restricts the forest to the named attributes. If empty, all
+	// attributes of the data set are used.
+	Attributes []string
+	// Seed seeds the random source used to sample and split the data set.
+	// Building twice with the same seed and data set produces identical
+	// forests. If zero, a random seed is chosen and recorded in Config.
+	Seed int64
+	// Workers is the number of trees built concurrently. If zero, it defaults
+	// to GOMAXPROCS. The worker count does not affect the resulting forest and
+	// is not saved with it.
+	Workers int
+	// Extended builds an Extended Isolation Forest, splitting nodes on random
+	// hyperplanes rather than a single attribute. All attributes used by the
+	// forest must be numerical.
+	Extended bool
+	// ExtensionLevel is the number of extra dimensions each hyperplane spans
+	// in an extended forest, between zero (a single attribute, like the
+	// standard algorithm) and the number of attributes minus one.
+	ExtensionLevel int
+	// SplitStrategy chooses how the nodes of a standard forest are split. If
+	// nil, RandomSplit is used. It cannot be set for an extended forest. Only
+	// the built in strategies are saved with a forest, so trees added to a
+	// loaded forest built with another strategy use RandomSplit.
+	SplitStrategy SplitStrategy
+	// UnseenCategories controls how values of categorical attributes that
+	// were not in the data set the forest was built from are scored.
+	UnseenCategories UnseenCategoryPolicy
+	// Contamination is the expected proportion of anomalies in the data set,
+	// between 0 and 0.5. If set, the forest's threshold is chosen so that this
+	// proportion of the data set is predicted anomalous. If zero, the
+	// threshold is DefaultThreshold.
+	Contamination float64
+}
+
+// DefaultForestConfig returns the configuration used by BuildForest.
+func DefaultForestConfig() ForestConfig {
+	return ForestConfig{
+		NumTrees:   NumTrees,
+		SampleSize: SampleSize,
+	}
+}
+
+func (c ForestConfig) validate(dataSet *DataSet) error {
+	if c.NumTrees <= 0 {
+		return fmt.Errorf("number of trees must be positive, got %d", c.NumTrees)
+	}
+	if c.SampleSize < 2 {
+		return fmt.Errorf("sample size must be at least 2, got %d", c.SampleSize)
+	}
+	if c.SampleSize > dataSet.Size {
+		return fmt.Errorf("sample size %d larger than data set size %d", c.SampleSize, dataSet.Size)
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("max depth must not be negative, got %d", c.MaxDepth)
+	}
+	if c.Workers < 0 {
+		return fmt.Errorf("workers must not be negative, got %d", c.Workers)
+	}
+	if c.UnseenCategories < UnseenFollowSplit || c.UnseenCategories > UnseenError {
+		return fmt.Errorf("unknown unseen category policy %d", c.UnseenCategories)
+	}
+	if c.Contamination < 0 || c.Contamination > 0.5 {
+		return fmt.Errorf("contamination must be between 0 and 0.5, got %f", c.Contamination)
+	}
+	if err := dataSet.containsAttributes(c.Attributes); err != nil {
+		return err
+	}
+	if sciForest, ok := c.SplitStrategy.(SCiForestSplit); ok {
+		if err := sciForest.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Extended {
+		if c.SplitStrategy != nil {
+			return fmt.Errorf("split strategy cannot be set for an extended forest")
+		}
+		return c.validateExtended(dataSet)
+	}
+	if c.ExtensionLevel != 0 {
+		return fmt.Errorf("extension level %d requires an extended forest", c.ExtensionLevel)
+	}
+	return nil
+}
+
+func (c ForestConfig) validateExtended(dataSet *DataSet) error {
+	attributes := dataSet.Attributes
+	if len(c.Attributes) > 0 {
+		attributeSet := dataSet.attributeSet()
+		attributes = make([]Attribute, len(c.Attributes))
+		for i, name := range c.Attributes {
+			attributes[i] = attributeSet[name]
+		}
+	}
+
+	for _, attr := range attributes {
+		if attr.Type != AttributeTypeNumerical {
+			return fmt.Errorf("extended forest requires numerical attributes, %s is not", attr.Name)
+		}
+	}
+	if c.ExtensionLevel < 0 || c.ExtensionLevel >= len(attributes) {
+		return fmt.Errorf("extension level must be between 0 and %d, got %d", len(attributes)-1, c.ExtensionLevel)
+	}
+	return nil
+}
+
+func (c ForestConfig) splitter() SplitStrategy {
+	if c.Extended {
+		return hyperplaneSplitter{extensionLevel: c.ExtensionLevel}
+	}
+	if c.SplitStrategy != nil {
+		return c.SplitStrategy
+	}
+	return RandomSplit{}
+}
+
+// ErrEmptyDataSet is returned when building a forest from a data set with no
+// rows.
+var ErrEmptyDataSet = errors.New("data set has no rows")
+
+// BuildForest is like TryBuildForest but panics if the forest cannot be built.
 func BuildForest(dataSet *DataSet) *IsolationForest {
+	forest, err := TryBuildForest(dataSet)
+	if err != nil {
+		panic(err.Error())
+	}
+	return forest
+}
+
+// TryBuildForest builds a forest using DefaultForestConfig. If the data set
+// has fewer rows than the default sample size, the whole data set is sampled.
+// It returns ErrEmptyDataSet if the data set has no rows, and an error if it
+// has a single row, since a sample needs at least two rows.
+func TryBuildForest(dataSet *DataSet) (*IsolationForest, error) {
+	config := DefaultForestConfig()
+	if dataSet.Size < config.SampleSize {
+		config.SampleSize = dataSet.Size
+	}
+	return BuildForestWithConfig(dataSet, config)
+}
+
+// BuildForestWithConfig builds a forest from dataSet according to config. It
+// returns ErrEmptyDataSet if the data set has no rows.
+func BuildForestWithConfig(dataSet *DataSet, config ForestConfig) (*IsolationForest, error) {
+	if dataSet.Size == 0 {
+		return nil, ErrEmptyDataSet
+	}
+	if err := config.validate(dataSet); err != nil {
+		return nil, fmt.Errorf("invalid forest config: %w", err)
+	}
+
+	if len(config.Attributes) > 0 {
+		subset, err := dataSet.With(config.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		dataSet = subset
+	}
+
+	if config.MaxDepth == 0 {
+		config.MaxDepth = int(math.Ceil(math.Log2(float64(config.SampleSize))))
+	}
+
+	for config.Seed == 0 {
+		config.Seed = rand.Int63()
+	}
+
 	forest := IsolationForest{
-		Trees:           []*IsolationTree{},
-		attributes:      make(map[string]Attribute),
-		expectedAverage: avgPathLen(SampleSize),
+		Trees:           make([]*IsolationTree, config.NumTrees),
+		attributes:      make([]Attribute, len(dataSet.Attributes)),
+		expectedAverage: avgPathLen(config.SampleSize),
+		threshold:       DefaultThreshold,
+		config:          config,
 	}
 
-	maxDepth := uint(math.Ceil(math.Log2(float64(SampleSize))))
+	if err := buildTrees(dataSet, forest.Trees, config.SampleSize, uint(config.MaxDepth), config.splitter(),
+		config.Workers, rand.New(rand.NewSource(config.Seed))); err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < NumTrees; i++ {
-		forest.Trees = append(forest.Trees,
-			&IsolationTree{Root: buildTree(dataSet.Sample(SampleSize), 0, maxDepth, make(map[Attribute]bool))})
+	copy(forest.attributes, dataSet.Attributes)
+	forest.categories = seenCategories(dataSet)
+
+	if config.Contamination > 0 {
+		if err := forest.fitThreshold(dataSet); err != nil {
+			return nil, err
+		}
 	}
 
-	for _, feature := range dataSet.Attributes {
-		forest.attributes[feature.Name] = feature
+	return &forest, nil
+}
+
+// buildTrees fills trees using up to workers goroutines, or GOMAXPROCS if
+// workers is not positive. Each tree gets its own random source seeded from r
+// before any work starts, so the result depends only on r and not on how trees
+// are scheduled across workers. If the splitter fails, the error for the
+// first tree it failed on is returned and trees is left incomplete.
+func buildTrees(dataSet *DataSet, trees []*IsolationTree, sampleSize int, maxDepth uint, splitter SplitStrategy, workers int, r *rand.Rand) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	seeds := make([]int64, len(trees))
+	for i := range seeds {
+		seeds[i] = r.Int63()
 	}
 
-	return &forest
+	errs := make([]error, len(trees))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				b := treeBuilder{
+					maxDepth: maxDepth,
+					splitter: splitter,
+					r:        rand.New(rand.NewSource(seeds[i])),
+				}
+				root, err := b.build(dataSet.SampleRand(sampleSize, b.r), 0, make(map[Attribute]bool))
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				trees[i] = newIsolationTree(root)
+			}
+		}()
+	}
+
+	for i := range trees {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("error building tree %d: %w", i, err)
+		}
+	}
+	return nil
 }
 
-func buildTree(dataSet *DataSet, depth uint, maxDepth uint, exclude map[Attribute]bool) *IsolationTreeNode {
+// treeBuilder holds the state shared by every node of a tree under
+// construction.
+type treeBuilder struct {
+	maxDepth uint
+	splitter SplitStrategy
+	r        *rand.Rand
+}
+
+func (b *treeBuilder) build(dataSet *DataSet, depth uint, exclude map[Attribute]bool) (*IsolationTreeNode, error) {
 	node := &IsolationTreeNode{}
 	node.remainingSize = dataSet.Size
-	if dataSet.Size <= 1 || depth >= maxDepth {
+	if dataSet.Size <= 1 || depth >= b.maxDepth {
 		node.isLeaf = true
 	} else {
-		split, left, right, err := dataSet.Split(exclude)
+		split, err := b.splitter.Split(dataSet, dataSet.candidates(exclude), b.r)
 		if errors.Is(err, ErrNotSplittable) {
 			node.isLeaf = true
+		} else if err != nil {
+			return nil, fmt.Errorf("error splitting node at depth %d: %w", depth, err)
+		} else if split == nil {
+			return nil, fmt.Errorf("split strategy returned no condition at depth %d", depth)
 		} else {
+			left, right := dataSet.splitOn(split)
 			node.split = split
 			// If a split has resulted in a dataset with no elements on one side,
 			// don't use that split again in this tree. This can happens when all
 			// elements have the same value for an attribute.
-			if left.Size == 0 || right.Size == 0 {
-				exclude = addExclusion(exclude, split.attribute)
+			if attrs := split.Attributes(); len(attrs) == 1 && (left.Size == 0 || right.Size == 0) {
+				exclude = addExclusion(exclude, attrs[0])
+			}
+			if node.left, err = b.build(left, depth+1, exclude); err != nil {
+				return nil, err
+			}
+			if node.right, err = b.build(right, depth+1, exclude); err != nil {
+				return nil, err
 			}
-			node.left = buildTree(left, depth+1, maxDepth, exclude)
-			node.right = buildTree(right, depth+1, maxDepth, exclude)
 		}
 	}
 
-	return node
+	return node, nil
 }
 
 func addExclusion(exclude map[Attribute]bool, attr Attribute) map[Attribute]bool {
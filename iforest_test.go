@@ -0,0 +1,400 @@
+package goiforest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func testDataSet(size int) *DataSet {
+	x := Attribute{Name: "X", Type: AttributeTypeNumerical}
+	y := Attribute{Name: "Y", Type: AttributeTypeNumerical}
+	color := Attribute{Name: "Color", Type: AttributeTypeCategorical}
+
+	ds := NewDataSet()
+	for _, attr := range []Attribute{x, y, color} {
+		ds.AddAttribute(attr)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	colors := []string{"red", "green", "blue"}
+	for i := 0; i < size; i++ {
+		ds.AddRow(map[Attribute]AttributeValue{
+			x:     {Num: r.NormFloat64()},
+			y:     {Num: r.NormFloat64()},
+			color: {Str: colors[r.Intn(len(colors))]},
+		})
+	}
+	return ds
+}
+
+func TestBuildForestWithConfig(t *testing.T) {
+	ds := testDataSet(100)
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees:   10,
+		SampleSize: 50,
+		Attributes: []string{"X", "Color"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(forest.Trees) != 10 {
+		t.Errorf("Expected 10 trees, got %d", len(forest.Trees))
+	}
+
+	if forest.expectedAverage != avgPathLen(50) {
+		t.Errorf("Expected average %f, got %f", avgPathLen(50), forest.expectedAverage)
+	}
+
+	if forest.Config().MaxDepth != 6 {
+		t.Errorf("Expected max depth 6, got %d", forest.Config().MaxDepth)
+	}
+
+	for _, attr := range forest.attributes {
+		if attr.Name == "Y" {
+			t.Errorf("Expected attribute Y to be excluded from forest")
+		}
+	}
+}
+
+func TestBuildForestWithConfigInvalid(t *testing.T) {
+	ds := testDataSet(100)
+
+	cases := []ForestConfig{
+		{NumTrees: 0, SampleSize: 50},
+		{NumTrees: 10, SampleSize: 0},
+		{NumTrees: 10, SampleSize: 1},
+		{NumTrees: 10, SampleSize: 101},
+		{NumTrees: 10, SampleSize: 50, MaxDepth: -1},
+		{NumTrees: 10, SampleSize: 50, Workers: -1},
+		{NumTrees: 10, SampleSize: 50, Attributes: []string{"Missing"}},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%+v", c), func(t *testing.T) {
+			if _, err := BuildForestWithConfig(ds, c); err == nil {
+				t.Errorf("Expected error for config %+v", c)
+			}
+		})
+	}
+}
+
+func TestBuildForestSmallDataSet(t *testing.T) {
+	forest := BuildForest(testDataSet(20))
+
+	if forest.Config().SampleSize != 20 {
+		t.Errorf("Expected sample size 20, got %d", forest.Config().SampleSize)
+	}
+}
+
+func TestBuildForestEmptyDataSet(t *testing.T) {
+	ds := testDataSet(0)
+
+	if _, err := TryBuildForest(ds); !errors.Is(err, ErrEmptyDataSet) {
+		t.Errorf("Expected ErrEmptyDataSet from TryBuildForest, got %v", err)
+	}
+	if _, err := BuildForestWithConfig(ds, DefaultForestConfig()); !errors.Is(err, ErrEmptyDataSet) {
+		t.Errorf("Expected ErrEmptyDataSet from BuildForestWithConfig, got %v", err)
+	}
+}
+
+func TestBuildForestSingleRow(t *testing.T) {
+	if _, err := TryBuildForest(testDataSet(1)); err == nil {
+		t.Error("Expected error building from a single row")
+	}
+}
+
+func TestBuildForestSeeded(t *testing.T) {
+	ds := testDataSet(200)
+	config := ForestConfig{NumTrees: 20, SampleSize: 64, Seed: 42}
+
+	a, err := BuildForestWithConfig(ds, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, err := BuildForestWithConfig(ds, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(a.Trees, b.Trees) {
+		t.Errorf("Expected identical trees for the same seed")
+	}
+
+	for i := 0; i < ds.Size; i++ {
+		row := ds.GetRowPlain(i)
+		if a.Score(row).Score != b.Score(row).Score {
+			t.Fatalf("Expected identical scores for row %d", i)
+		}
+	}
+}
+
+func TestBuildForestRecordsSeed(t *testing.T) {
+	ds := testDataSet(100)
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 5, SampleSize: 50})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if forest.Config().Seed == 0 {
+		t.Fatalf("Expected a seed to be recorded")
+	}
+
+	rebuilt, err := BuildForestWithConfig(ds, forest.Config())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(forest.Trees, rebuilt.Trees) {
+		t.Errorf("Expected rebuilding with the recorded config to reproduce the forest")
+	}
+}
+
+func TestBuildForestParallelMatchesSequential(t *testing.T) {
+	ds := testDataSet(200)
+
+	sequential, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 50, SampleSize: 64, Seed: 7, Workers: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, workers := range []int{2, 4, 16} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			parallel, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 50, SampleSize: 64, Seed: 7, Workers: workers})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(sequential.Trees, parallel.Trees) {
+				t.Errorf("Expected parallel build with %d workers to match sequential build", workers)
+			}
+		})
+	}
+}
+
+func TestScoreDataSet(t *testing.T) {
+	ds := testDataSet(1000)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 20, SampleSize: 64, Seed: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result, err := forest.ScoreDataSet(ds, BatchOptions{Workers: 3, PathLengths: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result.Scores) != ds.Size || len(result.AveragePathLengths) != ds.Size {
+		t.Fatalf("Expected %d scores and path lengths, got %d and %d",
+			ds.Size, len(result.Scores), len(result.AveragePathLengths))
+	}
+	if result.TreeTraces != nil {
+		t.Errorf("Expected no traces unless requested")
+	}
+
+	for i := 0; i < ds.Size; i++ {
+		expected := forest.Score(ds.GetRowPlain(i))
+		if result.Scores[i] != expected.Score {
+			t.Fatalf("Row %d: expected score %f, got %f", i, expected.Score, result.Scores[i])
+		}
+		if result.AveragePathLengths[i] != expected.AveragePathLength {
+			t.Fatalf("Row %d: expected path length %f, got %f",
+				i, expected.AveragePathLength, result.AveragePathLengths[i])
+		}
+	}
+}
+
+func TestScoreDataSetMissingAttribute(t *testing.T) {
+	ds := testDataSet(100)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 5, SampleSize: 50})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	excluded, err := ds.Excluding("X")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := forest.ScoreDataSet(excluded, BatchOptions{}); err == nil {
+		t.Errorf("Expected error scoring data set without attribute X")
+	}
+}
+
+func TestTryScoreErrors(t *testing.T) {
+	ds := testDataSet(100)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 5, SampleSize: 50})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = forest.TryScore(map[string]string{"X": "1", "Color": "red"})
+	var missing *MissingAttributeError
+	if !errors.As(err, &missing) || missing.Attribute != "Y" {
+		t.Errorf("Expected missing attribute Y, got %v", err)
+	}
+
+	_, err = forest.TryScore(map[string]string{"X": "1", "Y": "abc", "Color": "red"})
+	var parse *ParseError
+	if !errors.As(err, &parse) || parse.Attribute != "Y" || parse.Value != "abc" {
+		t.Errorf("Expected parse error for Y, got %v", err)
+	}
+
+	if _, err := forest.TryScore(map[string]string{"X": "1", "Y": "2", "Color": "red"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestParseAttributeValueUnknownType(t *testing.T) {
+	_, err := ParseAttributeValue(Attribute{Name: "Z", Type: AttributeType(9)}, "1")
+	if !errors.Is(err, ErrUnknownAttributeType) {
+		t.Errorf("Expected ErrUnknownAttributeType, got %v", err)
+	}
+}
+
+func TestScoreWithOptionsTrace(t *testing.T) {
+	ds := testDataSet(200)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 10, SampleSize: 64, Seed: 11})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	row := ds.GetRowPlain(0)
+	if result := forest.Score(row); result.TreeTraces != nil {
+		t.Errorf("Expected no traces by default")
+	}
+
+	result, err := forest.ScoreWithOptions(row, ScoreOptions{Trace: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.TreeTraces) != len(forest.Trees) {
+		t.Fatalf("Expected %d traces, got %d", len(forest.Trees), len(result.TreeTraces))
+	}
+
+	var total float64
+	for i, trace := range result.TreeTraces {
+		lastID := -1
+		for _, step := range trace.Steps {
+			if step.NodeID <= lastID {
+				t.Errorf("Tree %d: expected increasing node ids, got %d after %d", i, step.NodeID, lastID)
+			}
+			lastID = step.NodeID
+		}
+		if len(trace.Leaves) != 1 || trace.Leaves[0].Weight != 1 {
+			t.Fatalf("Tree %d: expected a single leaf with weight 1, got %v", i, trace.Leaves)
+		}
+		if trace.Leaves[0].ID <= lastID {
+			t.Errorf("Tree %d: expected leaf id after %d, got %d", i, lastID, trace.Leaves[0].ID)
+		}
+		total += trace.PathLength
+	}
+
+	if avg := total / float64(len(forest.Trees)); avg != result.AveragePathLength {
+		t.Errorf("Expected traced path lengths to average %f, got %f", result.AveragePathLength, avg)
+	}
+}
+
+func TestExtendedForest(t *testing.T) {
+	ds, err := testDataSet(500).Excluding("Color")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees:       50,
+		SampleSize:     128,
+		Seed:           13,
+		Extended:       true,
+		ExtensionLevel: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	inlier := forest.Score(map[string]string{"X": "0", "Y": "0"})
+	outlier := forest.Score(map[string]string{"X": "5", "Y": "-5"})
+	if outlier.Score <= inlier.Score {
+		t.Errorf("Expected outlier score %f to exceed inlier score %f", outlier.Score, inlier.Score)
+	}
+
+	traced, err := forest.ScoreWithOptions(map[string]string{"X": "5", "Y": "-5"}, ScoreOptions{Trace: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, step := range traced.TreeTraces[0].Steps {
+		if len(step.Normal) != 2 || len(step.Attributes) != 2 {
+			t.Errorf("Expected hyperplane steps over 2 attributes, got %v", step)
+		}
+	}
+}
+
+func TestExtendedForestInvalid(t *testing.T) {
+	ds := testDataSet(100)
+
+	cases := []ForestConfig{
+		{NumTrees: 10, SampleSize: 50, Extended: true},
+		{NumTrees: 10, SampleSize: 50, Extended: true, Attributes: []string{"X", "Y"}, ExtensionLevel: 2},
+		{NumTrees: 10, SampleSize: 50, Extended: true, Attributes: []string{"X", "Y"}, ExtensionLevel: -1},
+		{NumTrees: 10, SampleSize: 50, ExtensionLevel: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%+v", c), func(t *testing.T) {
+			if _, err := BuildForestWithConfig(ds, c); err == nil {
+				t.Errorf("Expected error for config %+v", c)
+			}
+		})
+	}
+}
+
+func TestScoreMissingValues(t *testing.T) {
+	ds := testDataSet(300)
+	x := Attribute{Name: "X", Type: AttributeTypeNumerical}
+	for i := 0; i < ds.Size; i += 10 {
+		ds.SetValue(i, x, AttributeValue{Missing: true})
+	}
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 20, SampleSize: 128, Seed: 17})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := forest.TryScore(map[string]string{"X": "NA", "Y": "0", "Color": "red"}); err == nil {
+		t.Errorf("Expected error scoring NA without missing tokens")
+	}
+
+	result, err := forest.ScoreWithOptions(map[string]string{"X": "NA", "Y": "0", "Color": "red"},
+		ScoreOptions{Trace: true, MissingTokens: DefaultMissingTokens})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.IsNaN(result.Score) || result.Score <= 0 || result.Score >= 1 {
+		t.Errorf("Expected a score between 0 and 1, got %f", result.Score)
+	}
+	if !result.Attributes[x].Missing {
+		t.Errorf("Expected X to be missing")
+	}
+
+	followedBoth := false
+	for i, trace := range result.TreeTraces {
+		var weight float64
+		for _, leaf := range trace.Leaves {
+			weight += leaf.Weight
+		}
+		if math.Abs(weight-1) > 1e-9 {
+			t.Errorf("Tree %d: expected leaf weights to sum to 1, got %f", i, weight)
+		}
+		for _, step := range trace.Steps {
+			if step.Branch == BranchBoth {
+				followedBoth = true
+			}
+		}
+	}
+	if !followedBoth {
+		t.Errorf("Expected at least one split on X to follow both branches")
+	}
+}
@@ -0,0 +1,172 @@
+package goiforest
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+)
+
+// AttributeImportance describes how much a forest relies on an attribute.
+// Splits counts the split nodes that depend on the attribute, Frequency is
+// Splits as a share of all split nodes and AverageDepth is the mean depth of
+// those nodes. Importance is only set when FeatureImportance is given a
+// reference data set.
+type AttributeImportance struct {
+	Attribute    Attribute
+	Splits       int
+	Frequency    float64
+	AverageDepth float64
+	Importance   float64
+}
+
+// FeatureImportance reports how the forest uses each of its attributes,
+// sorted from most to least important.
+//
+// If reference is not nil, it is scored and split into rows predicted
+// anomalous and the rest. Importance is then the mean contribution of the
+// attribute to the scores of anomalous rows, as computed by Explain, minus
+// its mean contribution to the scores of the other rows. Positive values mean
+// splits on the attribute isolate anomalies more readily than inliers. If no
+// rows are predicted anomalous, or none are not, the mean contribution of that
+// group is taken as zero. If reference is nil, attributes are sorted by Splits
+// instead. Attributes that custom conditions depend on but the forest does not
+// have are left out.
+func (f *IsolationForest) FeatureImportance(reference *DataSet) ([]AttributeImportance, error) {
+	importances := make([]AttributeImportance, len(f.attributes))
+	index := make(map[Attribute]*AttributeImportance, len(f.attributes))
+	for i, attr := range f.attributes {
+		importances[i].Attribute = attr
+		index[attr] = &importances[i]
+	}
+
+	totalSplits := 0
+	for _, tree := range f.Trees {
+		tree.Root.walk(0, func(node *IsolationTreeNode, depth int) {
+			if node.isLeaf {
+				return
+			}
+			totalSplits++
+			for _, attr := range node.split.Attributes() {
+				if importance, ok := index[attr]; ok {
+					importance.Splits++
+					importance.AverageDepth += float64(depth)
+				}
+			}
+		})
+	}
+
+	for i := range importances {
+		if importances[i].Splits > 0 {
+			importances[i].AverageDepth /= float64(importances[i].Splits)
+		}
+		if totalSplits > 0 {
+			importances[i].Frequency = float64(importances[i].Splits) / float64(totalSplits)
+		}
+	}
+
+	if reference == nil {
+		sort.SliceStable(importances, func(i, j int) bool {
+			return importances[i].Splits > importances[j].Splits
+		})
+		return importances, nil
+	}
+
+	if err := f.referenceImportance(reference, index); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(importances, func(i, j int) bool {
+		return importances[i].Importance > importances[j].Importance
+	})
+	return importances, nil
+}
+
+// importanceSums accumulates the contributions of the rows of a reference
+// data set, split by whether they were predicted anomalous.
+type importanceSums struct {
+	anomalous map[Attribute]float64
+	inlier    map[Attribute]float64
+	anomalies int
+	inliers   int
+}
+
+// referenceImportance sets the Importance of each attribute in index from the
+// contributions to the scores of the rows of reference. The sums for each
+// chunk of rows are added up in order so the result does not depend on
+// scheduling. If no rows are predicted anomalous, or none are not, the mean
+// contribution of that group is taken as zero.
+func (f *IsolationForest) referenceImportance(reference *DataSet, index map[Attribute]*AttributeImportance) error {
+	columns, err := f.columns(reference)
+	if err == nil && f.config.UnseenCategories == UnseenError {
+		err = f.checkUnseen(reference, columns)
+	}
+	if err != nil {
+		return fmt.Errorf("error scoring reference data set: %w", err)
+	}
+
+	chunks := make([]importanceSums, (reference.Size+batchChunkSize-1)/batchChunkSize)
+	f.scoreChunks(reference, columns, runtime.GOMAXPROCS(0), func() func(int, attributeValues, []int) {
+		traces := make([]TreeTrace, len(f.Trees))
+		contributions := make(map[Attribute]float64, len(f.attributes))
+		return func(i int, row attributeValues, unseen []int) {
+			f.sumRow(&chunks[i/batchChunkSize], row, unseen, traces, contributions)
+		}
+	})
+
+	anomalous := make(map[Attribute]float64, len(index))
+	inlier := make(map[Attribute]float64, len(index))
+	anomalies, inliers := 0, 0
+	for _, sums := range chunks {
+		for attr, c := range sums.anomalous {
+			anomalous[attr] += c
+		}
+		for attr, c := range sums.inlier {
+			inlier[attr] += c
+		}
+		anomalies += sums.anomalies
+		inliers += sums.inliers
+	}
+
+	for attr, importance := range index {
+		importance.Importance = 0
+		if anomalies > 0 {
+			importance.Importance += anomalous[attr] / float64(anomalies)
+		}
+		if inliers > 0 {
+			importance.Importance -= inlier[attr] / float64(inliers)
+		}
+	}
+	return nil
+}
+
+// sumRow scores row, adding its contributions to sums. traces and
+// contributions are reused between rows.
+func (f *IsolationForest) sumRow(sums *importanceSums, row attributeValues, unseen []int,
+	traces []TreeTrace, contributions map[Attribute]float64) {
+	if sums.anomalous == nil {
+		sums.anomalous = make(map[Attribute]float64, len(f.attributes))
+		sums.inlier = make(map[Attribute]float64, len(f.attributes))
+	}
+	for t := range traces {
+		traces[t].Steps = traces[t].Steps[:0]
+		traces[t].Leaves = traces[t].Leaves[:0]
+	}
+
+	score := f.score(f.policyPathLength(row, unseen, traces))
+	f.setContributions(contributions, traces)
+	if !f.traversed(unseen) {
+		for _, u := range unseen {
+			contributions[f.attributes[u]] = 1 / float64(len(unseen))
+		}
+	}
+
+	target := sums.inlier
+	if score > f.threshold {
+		target = sums.anomalous
+		sums.anomalies++
+	} else {
+		sums.inliers++
+	}
+	for attr, c := range contributions {
+		target[attr] += c
+	}
+}
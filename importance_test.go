@@ -0,0 +1,159 @@
+package goiforest
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFeatureImportance(t *testing.T) {
+	ds := testDataSet(1000)
+	x := Attribute{Name: "X", Type: AttributeTypeNumerical}
+	for i := 0; i < 20; i++ {
+		ds.SetValue(i, x, AttributeValue{Num: 10 + float64(i)})
+	}
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 100, SampleSize: 256, Seed: 29, Contamination: 0.02,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	structural, err := forest.FeatureImportance(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var frequency float64
+	for _, importance := range structural {
+		frequency += importance.Frequency
+		if importance.Importance != 0 {
+			t.Errorf("Expected no importance without a reference data set, got %v", importance)
+		}
+	}
+	if math.Abs(frequency-1) > 1e-9 {
+		t.Errorf("Expected split frequencies to sum to 1, got %f", frequency)
+	}
+
+	importances, err := forest.FeatureImportance(ds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if importances[0].Attribute != x || importances[0].Importance <= 0 {
+		t.Errorf("Expected X to be most important, got %v", importances)
+	}
+}
+
+func TestFeatureImportanceMatchesExplain(t *testing.T) {
+	ds := testDataSet(600)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 128, Seed: 31, Contamination: 0.05,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	anomalous := map[Attribute]float64{}
+	inlier := map[Attribute]float64{}
+	anomalies, inliers := 0, 0
+	for i := 0; i < ds.Size; i++ {
+		explanation, err := forest.Explain(ds.GetRowPlain(i), ScoreOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sums := inlier
+		if explanation.Score > forest.Threshold() {
+			sums = anomalous
+			anomalies++
+		} else {
+			inliers++
+		}
+		for attr, c := range explanation.Contributions {
+			sums[attr] += c
+		}
+	}
+
+	importances, err := forest.FeatureImportance(ds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, importance := range importances {
+		attr := importance.Attribute
+		expected := anomalous[attr]/float64(anomalies) - inlier[attr]/float64(inliers)
+		if math.Abs(importance.Importance-expected) > 1e-9 {
+			t.Errorf("Expected importance %f for %s, got %f", expected, attr.Name, importance.Importance)
+		}
+	}
+}
+
+func TestFeatureImportanceOneGroup(t *testing.T) {
+	ds := testDataSet(300)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 20, SampleSize: 128, Seed: 33})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	cases := map[string]struct {
+		threshold float64
+		reference *DataSet
+		sign      float64
+	}{
+		"only anomalies": {0, ds, 1},
+		"only inliers":   {1, ds, -1},
+		"no rows":        {DefaultThreshold, testDataSet(0), 0},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			forest.threshold = c.threshold
+			importances, err := forest.FeatureImportance(c.reference)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var total float64
+			for _, importance := range importances {
+				if importance.Importance*c.sign < 0 || c.sign == 0 && importance.Importance != 0 {
+					t.Errorf("Expected importance with sign %v for %s, got %f",
+						c.sign, importance.Attribute.Name, importance.Importance)
+				}
+				total += importance.Importance
+			}
+			if c.sign != 0 && total*c.sign <= 0 {
+				t.Errorf("Expected total importance with sign %v, got %f", c.sign, total)
+			}
+		})
+	}
+}
+
+// foreignSplit splits as ratioSplit but reports an attribute the forest does
+// not have in place of the denominator.
+type foreignSplit struct{}
+
+func (foreignSplit) Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error) {
+	c, err := ratioSplit{}.Split(d, candidates, r)
+	if err != nil {
+		return nil, err
+	}
+	c.(*ratioCondition).Denominator = Attribute{Name: "Z", Type: AttributeTypeNumerical}
+	return c, nil
+}
+
+func TestFeatureImportanceUnknownAttribute(t *testing.T) {
+	ds := positiveDataSet()
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 10, SampleSize: 128, Seed: 37, SplitStrategy: foreignSplit{}, Contamination: 0.05,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, reference := range []*DataSet{nil, ds} {
+		importances, err := forest.FeatureImportance(reference)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(importances) != 2 {
+			t.Errorf("Expected importance of the forest's 2 attributes only, got %v", importances)
+		}
+	}
+}
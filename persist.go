@@ -0,0 +1,829 @@
+package goiforest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// modelFormatVersion is written to every saved model and bumped whenever the
+// format changes in a way older readers cannot handle.
+//
+// Version 2 added hyperplane splits. Version 3 added the decision threshold.
+// Older models are loaded with DefaultThreshold. Version 4 added the split
+// strategy, which decides how trees added after loading are built. Version 5
+// added conditions registered with RegisterCondition, version 6 category set
+// splits, and version 7 the categories seen in training. Older models treat no
+// category as unseen.
+const modelFormatVersion = 7
+
+var binaryMagic = []byte("GIFB")
+
+const (
+	binaryNodeLeaf       byte = 0
+	binaryNodeSplit      byte = 1
+	binaryNodeHyperplane byte = 2
+	binaryNodeRegistered byte = 3
+	binaryNodeSet        byte = 4
+)
+
+const (
+	modelSplitHyperplane = "hyperplane"
+	modelSplitSet        = "set"
+)
+
+var ErrUnsupportedModel = errors.New("unsupported model format")
+
+// ErrCorruptModel is returned by Load when a model has no trees, or a
+// different number of trees than its header records.
+var ErrCorruptModel = errors.New("corrupt model")
+
+// conditionTypes holds the condition types registered with RegisterCondition.
+var conditionTypes = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	names  map[reflect.Type]string
+}{
+	byName: map[string]reflect.Type{},
+	names:  map[reflect.Type]string{},
+}
+
+// RegisterCondition registers the type of condition under name so forests
+// with conditions of that type can be saved and loaded. Conditions are saved
+// with encoding/json, so the type must marshal everything it needs, usually
+// by exporting its fields, and is loaded by unmarshaling into a new value of
+// the type. Like gob.Register, it is meant to be called from an init function
+// and panics if the name or type is already registered.
+func RegisterCondition(name string, condition Condition) {
+	if name == "" || name == modelSplitHyperplane || name == modelSplitSet {
+		panic(fmt.Sprintf("goiforest: condition name %q is reserved", name))
+	}
+
+	t := reflect.TypeOf(condition)
+	conditionTypes.Lock()
+	defer conditionTypes.Unlock()
+	if _, ok := conditionTypes.byName[name]; ok {
+		panic(fmt.Sprintf("goiforest: condition name %q registered twice", name))
+	}
+	if _, ok := conditionTypes.names[t]; ok {
+		panic(fmt.Sprintf("goiforest: condition type %v registered twice", t))
+	}
+	conditionTypes.byName[name] = t
+	conditionTypes.names[t] = name
+}
+
+// marshalCondition returns the registered name and JSON encoding of c.
+func marshalCondition(c Condition) (string, []byte, error) {
+	conditionTypes.RLock()
+	name, ok := conditionTypes.names[reflect.TypeOf(c)]
+	conditionTypes.RUnlock()
+	if !ok {
+		return "", nil, fmt.Errorf("condition type %T is not registered", c)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", nil, fmt.Errorf("error encoding condition %s: %w", name, err)
+	}
+	return name, data, nil
+}
+
+// unmarshalCondition decodes a condition of the type registered as name,
+// checking that it only depends on attributes of the forest.
+func unmarshalCondition(name string, data []byte, attributes []Attribute) (Condition, error) {
+	conditionTypes.RLock()
+	t, ok := conditionTypes.byName[name]
+	conditionTypes.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("condition %q is not registered", name)
+	}
+
+	var v reflect.Value
+	if t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem())
+	} else {
+		v = reflect.New(t)
+	}
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, fmt.Errorf("error decoding condition %s: %w", name, err)
+	}
+	if t.Kind() != reflect.Pointer {
+		v = v.Elem()
+	}
+
+	c := v.Interface().(Condition)
+	for _, attr := range c.Attributes() {
+		if !slices.Contains(attributes, attr) {
+			return nil, fmt.Errorf("condition %s depends on attribute %s, which is not in the model", name, attr.Name)
+		}
+	}
+	return c, nil
+}
+
+// modelHeader holds everything about a forest except its trees. The JSON
+// format stores it alongside the trees, the binary format stores it as a JSON
+// prefix ahead of the encoded trees.
+type modelHeader struct {
+	Version         int              `json:"version"`
+	Config          modelConfig      `json:"config"`
+	Attributes      []modelAttribute `json:"attributes"`
+	ExpectedAverage float64          `json:"expected_average"`
+	Threshold       float64          `json:"threshold"`
+	// Categories maps each categorical attribute to the values seen in
+	// training.
+	Categories map[string][]string `json:"categories,omitempty"`
+}
+
+type modelConfig struct {
+	NumTrees       int            `json:"num_trees"`
+	SampleSize     int            `json:"sample_size"`
+	MaxDepth       int            `json:"max_depth"`
+	Attributes     []string       `json:"attributes,omitempty"`
+	Seed           int64          `json:"seed"`
+	Extended       bool           `json:"extended,omitempty"`
+	ExtensionLevel int            `json:"extension_level,omitempty"`
+	SplitStrategy  *modelStrategy `json:"split_strategy,omitempty"`
+	// UnseenCategories is the name of the policy, empty for the default.
+	UnseenCategories string  `json:"unseen_categories,omitempty"`
+	Contamination    float64 `json:"contamination,omitempty"`
+}
+
+// modelStrategy describes the split strategy a forest was built with, so
+// trees added after loading it are built the same way. Kind is "random" or
+// "sciforest".
+type modelStrategy struct {
+	Kind              string `json:"kind"`
+	UniformCategories bool   `json:"uniform_categories,omitempty"`
+	CategorySets      bool   `json:"category_sets,omitempty"`
+	Candidates        int    `json:"candidates,omitempty"`
+	Attributes        int    `json:"attributes,omitempty"`
+}
+
+type modelAttribute struct {
+	Name string        `json:"name"`
+	Type AttributeType `json:"type"`
+}
+
+type modelJSON struct {
+	modelHeader
+	Trees []*modelNode `json:"trees"`
+}
+
+type modelNode struct {
+	Size  int         `json:"size"`
+	Split *modelSplit `json:"split,omitempty"`
+	Left  *modelNode  `json:"left,omitempty"`
+	Right *modelNode  `json:"right,omitempty"`
+}
+
+// modelSplit describes a node's split condition. Kind is empty for single
+// attribute splits. Hyperplane splits use Attributes and Normal, with
+// Threshold holding the offset. Category set splits use Attribute, with the
+// categories following each branch in Left and Right. Other kinds are the
+// names of registered conditions, whose JSON encoding is held in Data.
+type modelSplit struct {
+	Kind       string          `json:"kind,omitempty"`
+	Attribute  int             `json:"attribute"`
+	Value      string          `json:"value,omitempty"`
+	Threshold  float64         `json:"threshold"`
+	Attributes []int           `json:"attributes,omitempty"`
+	Normal     []float64       `json:"normal,omitempty"`
+	Left       []string        `json:"left,omitempty"`
+	Right      []string        `json:"right,omitempty"`
+	UnseenLeft bool            `json:"unseen_left,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// SaveJSON writes the forest as indented JSON. It is larger and slower to
+// read than the binary format written by Save, but easy to inspect.
+func (f *IsolationForest) SaveJSON(w io.Writer) error {
+	model := modelJSON{
+		modelHeader: f.header(),
+		Trees:       make([]*modelNode, len(f.Trees)),
+	}
+
+	for i, tree := range f.Trees {
+		node, err := toModelNode(tree.Root)
+		if err != nil {
+			return err
+		}
+		model.Trees[i] = node
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(model)
+}
+
+// Save writes the forest in a compact binary format that can be read back
+// with Load.
+func (f *IsolationForest) Save(w io.Writer) error {
+	header, err := json.Marshal(f.header())
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := binaryEncoder{w: bw}
+	enc.bytes(binaryMagic)
+	enc.uvarint(modelFormatVersion)
+	enc.uvarint(uint64(len(header)))
+	enc.bytes(header)
+	enc.uvarint(uint64(len(f.Trees)))
+
+	for _, tree := range f.Trees {
+		enc.node(tree.Root)
+	}
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return bw.Flush()
+}
+
+// Load reads a forest written by either Save or SaveJSON.
+func Load(r io.Reader) (*IsolationForest, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(binaryMagic))
+	if err == nil && bytes.Equal(magic, binaryMagic) {
+		return loadBinary(br)
+	}
+	return loadJSON(br)
+}
+
+func loadJSON(r io.Reader) (*IsolationForest, error) {
+	var model modelJSON
+	if err := json.NewDecoder(r).Decode(&model); err != nil {
+		return nil, fmt.Errorf("error decoding model: %w", err)
+	}
+
+	forest, err := fromHeader(model.modelHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	forest.Trees = make([]*IsolationTree, len(model.Trees))
+	for i, node := range model.Trees {
+		root, err := fromModelNode(node, forest.attributes)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding tree %d: %w", i, err)
+		}
+		forest.Trees[i] = newIsolationTree(root)
+	}
+	if err := forest.checkTrees(); err != nil {
+		return nil, err
+	}
+
+	return forest, nil
+}
+
+func loadBinary(r *bufio.Reader) (*IsolationForest, error) {
+	dec := binaryDecoder{r: r}
+	dec.bytes(uint64(len(binaryMagic)))
+	version := dec.uvarint()
+	if dec.err == nil && (version == 0 || version > modelFormatVersion) {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedModel, version)
+	}
+
+	var header modelHeader
+	headerBytes := dec.bytes(dec.uvarint())
+	if dec.err != nil {
+		return nil, fmt.Errorf("error decoding model: %w", dec.err)
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("error decoding model header: %w", err)
+	}
+	header.Version = int(version)
+
+	forest, err := fromHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trees are appended as they are read, so a corrupt count fails at the
+	// end of the input rather than allocating for trees that are not there.
+	n := dec.uvarint()
+	if dec.err != nil {
+		return nil, fmt.Errorf("error decoding model: %w", dec.err)
+	}
+	for i := uint64(0); i < n; i++ {
+		root := dec.node(forest.attributes)
+		if dec.err != nil {
+			return nil, fmt.Errorf("error decoding tree %d: %w", i, dec.err)
+		}
+		forest.Trees = append(forest.Trees, newIsolationTree(root))
+	}
+	if err := forest.checkTrees(); err != nil {
+		return nil, err
+	}
+
+	return forest, nil
+}
+
+// checkTrees checks the trees read from a model against its header.
+func (f *IsolationForest) checkTrees() error {
+	if len(f.Trees) == 0 {
+		return fmt.Errorf("%w: no trees", ErrCorruptModel)
+	}
+	if len(f.Trees) != f.config.NumTrees {
+		return fmt.Errorf("%w: header records %d trees, found %d", ErrCorruptModel, f.config.NumTrees, len(f.Trees))
+	}
+	return nil
+}
+
+func (f *IsolationForest) header() modelHeader {
+	header := modelHeader{
+		Version: modelFormatVersion,
+		Config: modelConfig{
+			NumTrees:       f.config.NumTrees,
+			SampleSize:     f.config.SampleSize,
+			MaxDepth:       f.config.MaxDepth,
+			Attributes:     f.config.Attributes,
+			Seed:           f.config.Seed,
+			Extended:       f.config.Extended,
+			ExtensionLevel: f.config.ExtensionLevel,
+			SplitStrategy:  toModelStrategy(f.config.SplitStrategy),
+			Contamination:  f.config.Contamination,
+		},
+		Attributes:      make([]modelAttribute, len(f.attributes)),
+		ExpectedAverage: f.expectedAverage,
+		Threshold:       f.threshold,
+	}
+	if f.config.UnseenCategories != UnseenFollowSplit {
+		header.Config.UnseenCategories = f.config.UnseenCategories.String()
+	}
+	for i, attr := range f.attributes {
+		header.Attributes[i] = modelAttribute{Name: attr.Name, Type: attr.Type}
+		if f.categories != nil && f.categories[i] != nil {
+			if header.Categories == nil {
+				header.Categories = map[string][]string{}
+			}
+			header.Categories[attr.Name] = sortedCategories(f.categories[i])
+		}
+	}
+	return header
+}
+
+func fromHeader(header modelHeader) (*IsolationForest, error) {
+	if header.Version <= 0 || header.Version > modelFormatVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedModel, header.Version)
+	}
+
+	forest := &IsolationForest{
+		attributes:      make([]Attribute, len(header.Attributes)),
+		expectedAverage: header.ExpectedAverage,
+		threshold:       header.Threshold,
+		config: ForestConfig{
+			NumTrees:       header.Config.NumTrees,
+			SampleSize:     header.Config.SampleSize,
+			MaxDepth:       header.Config.MaxDepth,
+			Attributes:     header.Config.Attributes,
+			Seed:           header.Config.Seed,
+			Extended:       header.Config.Extended,
+			ExtensionLevel: header.Config.ExtensionLevel,
+			Contamination:  header.Config.Contamination,
+		},
+	}
+	if header.Version < 3 {
+		forest.threshold = DefaultThreshold
+	}
+	strategy, err := fromModelStrategy(header.Config.SplitStrategy)
+	if err != nil {
+		return nil, err
+	}
+	forest.config.SplitStrategy = strategy
+	if header.Config.UnseenCategories != "" {
+		if forest.config.UnseenCategories, err = ParseUnseenCategoryPolicy(header.Config.UnseenCategories); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedModel, err)
+		}
+	}
+	if header.Version >= 7 {
+		forest.categories = make([]map[string]bool, len(header.Attributes))
+	}
+	for i, attr := range header.Attributes {
+		if attr.Type != AttributeTypeCategorical && attr.Type != AttributeTypeNumerical {
+			return nil, fmt.Errorf("attribute %s has unknown type %d", attr.Name, attr.Type)
+		}
+		forest.attributes[i] = Attribute{Name: attr.Name, Type: attr.Type}
+		if forest.categories != nil && attr.Type == AttributeTypeCategorical {
+			categories := header.Categories[attr.Name]
+			forest.categories[i] = make(map[string]bool, len(categories))
+			for _, category := range categories {
+				forest.categories[i][category] = true
+			}
+		}
+	}
+	return forest, nil
+}
+
+func toModelStrategy(strategy SplitStrategy) *modelStrategy {
+	switch strategy := strategy.(type) {
+	case RandomSplit:
+		return &modelStrategy{
+			Kind:              "random",
+			UniformCategories: strategy.UniformCategories,
+			CategorySets:      strategy.CategorySets,
+		}
+	case SCiForestSplit:
+		return &modelStrategy{Kind: "sciforest", Candidates: strategy.Candidates, Attributes: strategy.Attributes}
+	}
+	return nil
+}
+
+func fromModelStrategy(s *modelStrategy) (SplitStrategy, error) {
+	if s == nil {
+		return nil, nil
+	}
+	switch s.Kind {
+	case "random":
+		return RandomSplit{UniformCategories: s.UniformCategories, CategorySets: s.CategorySets}, nil
+	case "sciforest":
+		return SCiForestSplit{Candidates: s.Candidates, Attributes: s.Attributes}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown split strategy %q", ErrUnsupportedModel, s.Kind)
+}
+
+func toModelNode(n *IsolationTreeNode) (*modelNode, error) {
+	node := &modelNode{Size: n.remainingSize}
+	if n.isLeaf {
+		return node, nil
+	}
+
+	switch split := n.split.(type) {
+	case *splitCondition:
+		node.Split = &modelSplit{
+			Attribute: split.index,
+			Value:     split.strVal,
+			Threshold: split.numVal,
+		}
+	case *hyperplaneCondition:
+		node.Split = &modelSplit{
+			Kind:       modelSplitHyperplane,
+			Threshold:  split.offset,
+			Attributes: split.indices,
+			Normal:     split.normal,
+		}
+	case *setCondition:
+		node.Split = &modelSplit{
+			Kind:       modelSplitSet,
+			Attribute:  split.index,
+			Left:       split.left,
+			Right:      split.right,
+			UnseenLeft: split.unseenLeft,
+		}
+	default:
+		name, data, err := marshalCondition(split)
+		if err != nil {
+			return nil, err
+		}
+		node.Split = &modelSplit{Kind: name, Data: data}
+	}
+
+	var err error
+	if node.Left, err = toModelNode(n.left); err != nil {
+		return nil, err
+	}
+	if node.Right, err = toModelNode(n.right); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func fromModelNode(n *modelNode, attributes []Attribute) (*IsolationTreeNode, error) {
+	if n == nil {
+		return nil, fmt.Errorf("missing node")
+	}
+
+	node := &IsolationTreeNode{remainingSize: n.Size}
+	if n.Split == nil {
+		node.isLeaf = true
+		return node, nil
+	}
+
+	var err error
+	if node.split, err = fromModelSplit(n.Split, attributes); err != nil {
+		return nil, err
+	}
+	if node.left, err = fromModelNode(n.Left, attributes); err != nil {
+		return nil, err
+	}
+	if node.right, err = fromModelNode(n.Right, attributes); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func fromModelSplit(s *modelSplit, attributes []Attribute) (Condition, error) {
+	switch s.Kind {
+	case "":
+		if s.Attribute < 0 || s.Attribute >= len(attributes) {
+			return nil, fmt.Errorf("split attribute index %d out of range", s.Attribute)
+		}
+		return &splitCondition{
+			attribute: attributes[s.Attribute],
+			index:     s.Attribute,
+			strVal:    s.Value,
+			numVal:    s.Threshold,
+		}, nil
+	case modelSplitHyperplane:
+		if len(s.Attributes) != len(s.Normal) {
+			return nil, fmt.Errorf("hyperplane has %d attributes but %d normal components",
+				len(s.Attributes), len(s.Normal))
+		}
+		c := &hyperplaneCondition{
+			attributes: make([]Attribute, len(s.Attributes)),
+			indices:    s.Attributes,
+			normal:     s.Normal,
+			offset:     s.Threshold,
+		}
+		for i, idx := range s.Attributes {
+			if idx < 0 || idx >= len(attributes) {
+				return nil, fmt.Errorf("split attribute index %d out of range", idx)
+			}
+			c.attributes[i] = attributes[idx]
+		}
+		return c, nil
+	case modelSplitSet:
+		if s.Attribute < 0 || s.Attribute >= len(attributes) {
+			return nil, fmt.Errorf("split attribute index %d out of range", s.Attribute)
+		}
+		if attributes[s.Attribute].Type != AttributeTypeCategorical {
+			return nil, fmt.Errorf("category set split on numerical attribute %s", attributes[s.Attribute].Name)
+		}
+		return newSetCondition(attributes[s.Attribute], s.Attribute, s.Left, s.Right, s.UnseenLeft), nil
+	}
+	return unmarshalCondition(s.Kind, s.Data, attributes)
+}
+
+// binaryEncoder writes the binary model format, remembering the first error
+// so callers can check once at the end.
+type binaryEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *binaryEncoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *binaryEncoder) uvarint(v uint64) {
+	e.bytes(e.buf[:binary.PutUvarint(e.buf[:], v)])
+}
+
+func (e *binaryEncoder) float(v float64) {
+	binary.LittleEndian.PutUint64(e.buf[:8], math.Float64bits(v))
+	e.bytes(e.buf[:8])
+}
+
+func (e *binaryEncoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+func (e *binaryEncoder) strings(s []string) {
+	e.uvarint(uint64(len(s)))
+	for _, v := range s {
+		e.string(v)
+	}
+}
+
+func (e *binaryEncoder) bool(v bool) {
+	if v {
+		e.bytes([]byte{1})
+	} else {
+		e.bytes([]byte{0})
+	}
+}
+
+func (e *binaryEncoder) node(n *IsolationTreeNode) {
+	if n.isLeaf {
+		e.bytes([]byte{binaryNodeLeaf})
+		e.uvarint(uint64(n.remainingSize))
+		return
+	}
+
+	switch split := n.split.(type) {
+	case *splitCondition:
+		e.bytes([]byte{binaryNodeSplit})
+		e.uvarint(uint64(n.remainingSize))
+		e.uvarint(uint64(split.index))
+		if split.attribute.Type == AttributeTypeCategorical {
+			e.string(split.strVal)
+		} else {
+			e.float(split.numVal)
+		}
+	case *hyperplaneCondition:
+		e.bytes([]byte{binaryNodeHyperplane})
+		e.uvarint(uint64(n.remainingSize))
+		e.uvarint(uint64(len(split.attributes)))
+		for i, idx := range split.indices {
+			e.uvarint(uint64(idx))
+			e.float(split.normal[i])
+		}
+		e.float(split.offset)
+	case *setCondition:
+		e.bytes([]byte{binaryNodeSet})
+		e.uvarint(uint64(n.remainingSize))
+		e.uvarint(uint64(split.index))
+		e.strings(split.left)
+		e.strings(split.right)
+		e.bool(split.unseenLeft)
+	default:
+		name, data, err := marshalCondition(split)
+		if err != nil {
+			if e.err == nil {
+				e.err = err
+			}
+			return
+		}
+		e.bytes([]byte{binaryNodeRegistered})
+		e.uvarint(uint64(n.remainingSize))
+		e.string(name)
+		e.string(string(data))
+	}
+	e.node(n.left)
+	e.node(n.right)
+}
+
+// binaryDecoder reads the binary model format. Once an error occurs all
+// further reads return zero values and the error is kept in err.
+type binaryDecoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+// binaryChunkSize is the most bytes allocated up front for a read. Longer
+// reads grow their buffer as the data arrives.
+const binaryChunkSize = 64 << 10
+
+// bytes reads n bytes. A length read from a corrupt model fails at the end of
+// the input rather than allocating more than the input holds.
+func (d *binaryDecoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n <= binaryChunkSize {
+		b := make([]byte, n)
+		_, d.err = io.ReadFull(d.r, b)
+		return b
+	}
+	if n > math.MaxInt64 {
+		d.err = fmt.Errorf("length %d out of range", n)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	read, err := io.CopyN(&buf, d.r, int64(n))
+	if err == io.EOF && read > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	d.err = err
+	return buf.Bytes()
+}
+
+func (d *binaryDecoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	var b byte
+	b, d.err = d.r.ReadByte()
+	return b
+}
+
+func (d *binaryDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var v uint64
+	v, d.err = binary.ReadUvarint(d.r)
+	return v
+}
+
+func (d *binaryDecoder) float() float64 {
+	b := d.bytes(8)
+	if d.err != nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (d *binaryDecoder) string() string {
+	return string(d.bytes(d.uvarint()))
+}
+
+// strings reads a list of strings. It stops at the first error, so a corrupt
+// length cannot allocate more than the model holds.
+func (d *binaryDecoder) strings() []string {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	var s []string
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		s = append(s, d.string())
+	}
+	return s
+}
+
+func (d *binaryDecoder) bool() bool {
+	return d.byte() != 0
+}
+
+// attribute reads an attribute index, returning the index and the attribute
+// at that position.
+func (d *binaryDecoder) attribute(attributes []Attribute) (int, Attribute) {
+	idx := d.uvarint()
+	if d.err == nil && idx >= uint64(len(attributes)) {
+		d.err = fmt.Errorf("split attribute index %d out of range", idx)
+	}
+	if d.err != nil {
+		return 0, Attribute{}
+	}
+	return int(idx), attributes[idx]
+}
+
+func (d *binaryDecoder) node(attributes []Attribute) *IsolationTreeNode {
+	tag := d.byte()
+	node := &IsolationTreeNode{remainingSize: int(d.uvarint())}
+	if d.err != nil {
+		return nil
+	}
+
+	switch tag {
+	case binaryNodeLeaf:
+		node.isLeaf = true
+		return node
+	case binaryNodeSplit:
+		idx, attr := d.attribute(attributes)
+		if d.err != nil {
+			return nil
+		}
+		split := &splitCondition{attribute: attr, index: idx}
+		if attr.Type == AttributeTypeCategorical {
+			split.strVal = d.string()
+		} else {
+			split.numVal = d.float()
+		}
+		node.split = split
+	case binaryNodeHyperplane:
+		n := d.uvarint()
+		if d.err == nil && n > uint64(len(attributes)) {
+			d.err = fmt.Errorf("hyperplane spans %d attributes, more than the %d in the model", n, len(attributes))
+		}
+		if d.err != nil {
+			return nil
+		}
+		split := &hyperplaneCondition{
+			attributes: make([]Attribute, n),
+			indices:    make([]int, n),
+			normal:     make([]float64, n),
+		}
+		for i := range split.attributes {
+			split.indices[i], split.attributes[i] = d.attribute(attributes)
+			split.normal[i] = d.float()
+		}
+		split.offset = d.float()
+		node.split = split
+	case binaryNodeSet:
+		idx, attr := d.attribute(attributes)
+		if d.err == nil && attr.Type != AttributeTypeCategorical {
+			d.err = fmt.Errorf("category set split on numerical attribute %s", attr.Name)
+		}
+		left, right := d.strings(), d.strings()
+		unseenLeft := d.bool()
+		if d.err != nil {
+			return nil
+		}
+		node.split = newSetCondition(attr, idx, left, right, unseenLeft)
+	case binaryNodeRegistered:
+		name := d.string()
+		data := d.bytes(d.uvarint())
+		if d.err != nil {
+			return nil
+		}
+		node.split, d.err = unmarshalCondition(name, data, attributes)
+	default:
+		d.err = fmt.Errorf("unknown node type %d", tag)
+		return nil
+	}
+
+	if d.err != nil {
+		return nil
+	}
+	node.left = d.node(attributes)
+	node.right = d.node(attributes)
+	return node
+}
@@ -0,0 +1,296 @@
+package goiforest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	ds := testDataSet(200)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 20, SampleSize: 64, Seed: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testSaveLoad(t, ds, forest)
+}
+
+func TestSaveLoadExtended(t *testing.T) {
+	ds, err := testDataSet(200).Excluding("Color")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 64, Seed: 3, Extended: true, ExtensionLevel: 1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testSaveLoad(t, ds, forest)
+}
+
+func TestSaveLoadSCiForest(t *testing.T) {
+	ds := testDataSet(200)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 64, Seed: 3, SplitStrategy: SCiForestSplit{Candidates: 4},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testSaveLoad(t, ds, forest)
+}
+
+func testSaveLoad(t *testing.T, ds *DataSet, forest *IsolationForest) {
+	t.Helper()
+
+	formats := map[string]func(*IsolationForest, *bytes.Buffer) error{
+		"binary": func(f *IsolationForest, b *bytes.Buffer) error { return f.Save(b) },
+		"json":   func(f *IsolationForest, b *bytes.Buffer) error { return f.SaveJSON(b) },
+	}
+
+	for name, save := range formats {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := save(forest, &buf); err != nil {
+				t.Fatalf("Unexpected error saving: %v", err)
+			}
+
+			loaded, err := Load(&buf)
+			if err != nil {
+				t.Fatalf("Unexpected error loading: %v", err)
+			}
+
+			if !reflect.DeepEqual(forest, loaded) {
+				t.Errorf("Expected loaded forest to equal saved forest")
+			}
+
+			for i := 0; i < ds.Size; i++ {
+				row := ds.GetRowPlain(i)
+				if forest.Score(row).Score != loaded.Score(row).Score {
+					t.Fatalf("Expected identical scores for row %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadUnsupportedVersion(t *testing.T) {
+	for _, version := range []uint64{0, modelFormatVersion + 1, 999} {
+		t.Run(fmt.Sprintf("json version %d", version), func(t *testing.T) {
+			_, err := Load(strings.NewReader(fmt.Sprintf(`{"version": %d, "trees": []}`, version)))
+			if !errors.Is(err, ErrUnsupportedModel) {
+				t.Errorf("Expected ErrUnsupportedModel, got %v", err)
+			}
+		})
+		t.Run(fmt.Sprintf("binary version %d", version), func(t *testing.T) {
+			b := binary.AppendUvarint(append([]byte{}, binaryMagic...), version)
+			_, err := Load(bytes.NewReader(b))
+			if !errors.Is(err, ErrUnsupportedModel) {
+				t.Errorf("Expected ErrUnsupportedModel, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadTruncated(t *testing.T) {
+	forest, err := BuildForestWithConfig(testDataSet(100), ForestConfig{NumTrees: 5, SampleSize: 32, Seed: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := forest.Save(&buf); err != nil {
+		t.Fatalf("Unexpected error saving: %v", err)
+	}
+
+	saved := buf.Bytes()
+	for n := len(binaryMagic); n < len(saved); n++ {
+		if _, err := Load(bytes.NewReader(saved[:n])); err == nil {
+			t.Fatalf("Expected error loading the first %d of %d bytes", n, len(saved))
+		}
+	}
+}
+
+func TestLoadMissingTrees(t *testing.T) {
+	cases := map[string]func(f *IsolationForest){
+		"no trees": func(f *IsolationForest) {
+			f.Trees = nil
+			f.config.NumTrees = 0
+		},
+		"fewer trees than header": func(f *IsolationForest) {
+			f.Trees = f.Trees[:3]
+		},
+	}
+
+	for name, truncate := range cases {
+		forest, err := BuildForestWithConfig(testDataSet(100), ForestConfig{NumTrees: 5, SampleSize: 32, Seed: 3})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		truncate(forest)
+
+		t.Run(name+" json", func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := forest.SaveJSON(&buf); err != nil {
+				t.Fatalf("Unexpected error saving: %v", err)
+			}
+			if _, err := Load(&buf); !errors.Is(err, ErrCorruptModel) {
+				t.Errorf("Expected ErrCorruptModel, got %v", err)
+			}
+		})
+		t.Run(name+" binary", func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := forest.Save(&buf); err != nil {
+				t.Fatalf("Unexpected error saving: %v", err)
+			}
+			if _, err := Load(&buf); !errors.Is(err, ErrCorruptModel) {
+				t.Errorf("Expected ErrCorruptModel, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	header := []byte(`{"config":{"num_trees":1,"sample_size":1},"attributes":[{"name":"X","type":1}]}`)
+	model := func(headerLength, trees uint64) []byte {
+		b := append([]byte{}, binaryMagic...)
+		b = binary.AppendUvarint(b, modelFormatVersion)
+		b = binary.AppendUvarint(b, headerLength)
+		b = append(b, header...)
+		return binary.AppendUvarint(b, trees)
+	}
+
+	cases := map[string][]byte{
+		"header length overflows int":   model(1<<63+5, 1),
+		"header length past end":        model(1<<40, 1),
+		"tree count past end":           model(uint64(len(header)), 1<<62),
+		"string length past end":        append(model(uint64(len(header)), 1), binaryNodeRegistered, 1, 0xff, 0xff, 0xff, 0xff, 0x0f),
+		"unknown node type":             append(model(uint64(len(header)), 1), 9, 1),
+		"attribute index out of range":  append(model(uint64(len(header)), 1), binaryNodeSplit, 2, 5),
+		"hyperplane spans too many":     append(model(uint64(len(header)), 1), binaryNodeHyperplane, 2, 2),
+		"set split on numerical column": append(model(uint64(len(header)), 1), binaryNodeSet, 2, 0, 0, 0, 0),
+	}
+
+	for name, b := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Load(bytes.NewReader(b)); err == nil {
+				t.Errorf("Expected error loading corrupt model")
+			}
+		})
+	}
+}
+
+func TestLoadVersion1DefaultThreshold(t *testing.T) {
+	forest, err := Load(strings.NewReader(`{
+		"version": 1,
+		"config": {"num_trees": 1, "sample_size": 1},
+		"attributes": [{"name": "X", "type": 1}],
+		"expected_average": 0,
+		"trees": [{"size": 1}]
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if forest.Threshold() != DefaultThreshold {
+		t.Errorf("Expected threshold %f, got %f", DefaultThreshold, forest.Threshold())
+	}
+}
+
+// unregisteredCondition is a ratioCondition whose type is not registered.
+type unregisteredCondition struct {
+	ratioCondition
+}
+
+type unregisteredSplit struct{}
+
+func (unregisteredSplit) Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error) {
+	c, err := ratioSplit{}.Split(d, candidates, r)
+	if err != nil {
+		return nil, err
+	}
+	return &unregisteredCondition{*c.(*ratioCondition)}, nil
+}
+
+func TestSaveLoadRegisteredCondition(t *testing.T) {
+	ds := positiveDataSet()
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 5, SampleSize: 64, Seed: 3, SplitStrategy: ratioSplit{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := forest.SaveJSON(&buf); err != nil {
+		t.Fatalf("Unexpected error saving: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"kind": "ratio"`) {
+		t.Errorf("Expected JSON to name the registered condition")
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error loading: %v", err)
+	}
+	if !reflect.DeepEqual(forest.Trees, loaded.Trees) {
+		t.Errorf("Expected loaded trees to equal saved trees")
+	}
+
+	forest, err = BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 5, SampleSize: 64, Seed: 3, SplitStrategy: unregisteredSplit{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := forest.Save(io.Discard); err == nil {
+		t.Errorf("Expected error saving an unregistered condition")
+	}
+	if err := forest.SaveJSON(io.Discard); err == nil {
+		t.Errorf("Expected error saving an unregistered condition as JSON")
+	}
+}
+
+func TestRegisterConditionPanics(t *testing.T) {
+	for _, name := range []string{"ratio", "", "hyperplane", "other"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registering %q to panic", name)
+				}
+			}()
+			// The type is already registered as ratio.
+			RegisterCondition(name, &ratioCondition{})
+		}()
+	}
+}
+
+func TestSaveLoadCategorySets(t *testing.T) {
+	ds := testDataSet(200)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 64, Seed: 3, SplitStrategy: RandomSplit{CategorySets: true},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testSaveLoad(t, ds, forest)
+}
+
+func TestSaveLoadUnseenCategories(t *testing.T) {
+	ds := testDataSet(200)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 64, Seed: 3, UnseenCategories: UnseenRandom,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	testSaveLoad(t, ds, forest)
+}
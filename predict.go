@@ -0,0 +1,57 @@
+package goiforest
+
+import "fmt"
+
+// DefaultThreshold is the score above which a forest built without a
+// contamination predicts a data point is anomalous.
+const DefaultThreshold = 0.5
+
+// Threshold returns the score above which the forest predicts a data point is
+// anomalous.
+func (f *IsolationForest) Threshold() float64 {
+	return f.threshold
+}
+
+// fitThreshold sets the threshold so that the configured contamination of
+// dataSet scores above it.
+func (f *IsolationForest) fitThreshold(dataSet *DataSet) error {
+	result, err := f.ScoreDataSet(dataSet, BatchOptions{Workers: f.config.Workers})
+	if err != nil {
+		return fmt.Errorf("error scoring training data: %w", err)
+	}
+	f.threshold = quantile(result.Scores, 1-f.config.Contamination)
+	return nil
+}
+
+// DecisionFunction returns the score of dataPoint minus the forest's
+// threshold. Positive values are predicted anomalous.
+func (f *IsolationForest) DecisionFunction(dataPoint map[string]string) (float64, error) {
+	result, err := f.TryScore(dataPoint)
+	if err != nil {
+		return 0, err
+	}
+	return result.Score - f.threshold, nil
+}
+
+// Predict reports whether dataPoint is predicted anomalous.
+func (f *IsolationForest) Predict(dataPoint map[string]string) (bool, error) {
+	decision, err := f.DecisionFunction(dataPoint)
+	if err != nil {
+		return false, err
+	}
+	return decision > 0, nil
+}
+
+// PredictDataSet reports whether each row of dataSet is predicted anomalous.
+func (f *IsolationForest) PredictDataSet(dataSet *DataSet) ([]bool, error) {
+	result, err := f.ScoreDataSet(dataSet, BatchOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	predictions := make([]bool, len(result.Scores))
+	for i, score := range result.Scores {
+		predictions[i] = score > f.threshold
+	}
+	return predictions, nil
+}
@@ -0,0 +1,65 @@
+package goiforest
+
+import (
+	"testing"
+)
+
+func TestContaminationThreshold(t *testing.T) {
+	ds := testDataSet(1000)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees:      50,
+		SampleSize:    128,
+		Seed:          19,
+		Contamination: 0.1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	predictions, err := forest.PredictDataSet(ds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	anomalies := 0
+	for _, p := range predictions {
+		if p {
+			anomalies++
+		}
+	}
+	if anomalies < 90 || anomalies > 110 {
+		t.Errorf("Expected around 100 anomalies, got %d", anomalies)
+	}
+
+	for i := 0; i < 50; i++ {
+		row := ds.GetRowPlain(i)
+		decision, err := forest.DecisionFunction(row)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		predicted, err := forest.Predict(row)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if predicted != predictions[i] || predicted != (decision > 0) {
+			t.Errorf("Row %d: inconsistent prediction %v, batch %v, decision %f",
+				i, predicted, predictions[i], decision)
+		}
+	}
+}
+
+func TestDefaultThreshold(t *testing.T) {
+	forest := BuildForest(testDataSet(100))
+	if forest.Threshold() != DefaultThreshold {
+		t.Errorf("Expected threshold %f, got %f", DefaultThreshold, forest.Threshold())
+	}
+}
+
+func TestContaminationInvalid(t *testing.T) {
+	ds := testDataSet(100)
+	for _, c := range []float64{-0.1, 0.6} {
+		if _, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 5, SampleSize: 50, Contamination: c}); err == nil {
+			t.Errorf("Expected error for contamination %f", c)
+		}
+	}
+}
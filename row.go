@@ -0,0 +1,111 @@
+package goiforest
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRowSchema is returned by ScoreVector when a row was not created for a
+// forest with the same attributes.
+var ErrRowSchema = errors.New("row attributes do not match forest")
+
+// Attributes returns the attributes of the forest, in the order used to index
+// a Row.
+func (f *IsolationForest) Attributes() []Attribute {
+	attributes := make([]Attribute, len(f.attributes))
+	copy(attributes, f.attributes)
+	return attributes
+}
+
+// Row is a data point laid out in the order of a forest's attributes, so it
+// can be scored by ScoreVector without parsing or allocating. Rows are
+// created by NewRow and are meant to be reused; they must not be used
+// concurrently.
+type Row struct {
+	attributes []Attribute
+	values     attributeValues
+}
+
+// NewRow returns a row for the forest with every value missing.
+func (f *IsolationForest) NewRow() *Row {
+	row := &Row{
+		attributes: f.attributes,
+		values:     make(attributeValues, len(f.attributes)),
+	}
+	row.Reset()
+	return row
+}
+
+// Index returns the position of the named attribute in the row.
+func (r *Row) Index(name string) (int, bool) {
+	for i, attr := range r.attributes {
+		if attr.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// SetNum sets the numerical value at position i. It panics if the attribute
+// at i is not numerical.
+func (r *Row) SetNum(i int, v float64) {
+	r.checkType(i, AttributeTypeNumerical, "numerical")
+	r.values[i] = AttributeValue{Num: v}
+}
+
+// SetStr sets the categorical value at position i. It panics if the attribute
+// at i is not categorical.
+func (r *Row) SetStr(i int, v string) {
+	r.checkType(i, AttributeTypeCategorical, "categorical")
+	r.values[i] = AttributeValue{Str: v}
+}
+
+// SetMissing marks the value at position i as missing.
+func (r *Row) SetMissing(i int) {
+	r.values[i] = AttributeValue{Missing: true}
+}
+
+// Reset marks every value of the row as missing.
+func (r *Row) Reset() {
+	for i := range r.values {
+		r.values[i] = AttributeValue{Missing: true}
+	}
+}
+
+func (r *Row) checkType(i int, typ AttributeType, name string) {
+	if r.attributes[i].Type != typ {
+		panic(fmt.Sprintf("attribute %s is not %s", r.attributes[i].Name, name))
+	}
+}
+
+// Value returns the value of the attribute at position i.
+func (r *Row) Value(i int) AttributeValue {
+	return r.values[i]
+}
+
+// ScoreVector scores a row created by NewRow. Unlike TryScore it does not
+// parse values or allocate, so it suits scoring in a hot loop. It returns
+// ErrRowSchema if the row was created for a forest with different attributes,
+// or an *UnseenCategoryError as described by UnseenError.
+func (f *IsolationForest) ScoreVector(row *Row) (float64, error) {
+	if !sameAttributes(row.attributes, f.attributes) {
+		return 0, ErrRowSchema
+	}
+	unseen := f.unseenValues(row, nil)
+	if len(unseen) > 0 && f.config.UnseenCategories == UnseenError {
+		return 0, f.unseenError(row, unseen)
+	}
+	return f.score(f.policyPathLength(row, unseen, nil)), nil
+}
+
+func sameAttributes(a, b []Attribute) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
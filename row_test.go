@@ -0,0 +1,81 @@
+package goiforest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScoreVector(t *testing.T) {
+	ds := testDataSet(500)
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 50, SampleSize: 128, Seed: 31})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	row := forest.NewRow()
+	x, _ := row.Index("X")
+	y, _ := row.Index("Y")
+	color, _ := row.Index("Color")
+	if _, ok := row.Index("Z"); ok {
+		t.Errorf("Expected unknown attribute not to be found")
+	}
+
+	for i := 0; i < 20; i++ {
+		values := ds.GetRowWithNames(i)
+		row.SetNum(x, values["X"].Num)
+		row.SetNum(y, values["Y"].Num)
+		row.SetStr(color, values["Color"].Str)
+
+		score, err := forest.ScoreVector(row)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := forest.Score(ds.GetRowPlain(i)).Score
+		if score != expected {
+			t.Errorf("Row %d: expected score %f, got %f", i, expected, score)
+		}
+	}
+
+	row.SetMissing(x)
+	missing, err := forest.ScoreVector(row)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	point := ds.GetRowPlain(19)
+	point["X"] = "NA"
+	expected, err := forest.ScoreWithOptions(point, ScoreOptions{MissingTokens: DefaultMissingTokens})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if missing != expected.Score {
+		t.Errorf("Expected missing score %f, got %f", expected.Score, missing)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		forest.ScoreVector(row)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %f", allocs)
+	}
+}
+
+func TestScoreVectorSchema(t *testing.T) {
+	forest := BuildForest(testDataSet(100))
+	other, err := BuildForestWithConfig(testDataSet(100), ForestConfig{NumTrees: 10, SampleSize: 50, Attributes: []string{"X", "Y"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := forest.ScoreVector(other.NewRow()); !errors.Is(err, ErrRowSchema) {
+		t.Errorf("Expected ErrRowSchema, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic setting a number on a categorical attribute")
+		}
+	}()
+	row := forest.NewRow()
+	color, _ := row.Index("Color")
+	row.SetNum(color, 1)
+}
@@ -0,0 +1,221 @@
+package goiforest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// String returns "numerical" or "categorical".
+func (t AttributeType) String() string {
+	switch t {
+	case AttributeTypeNumerical:
+		return "numerical"
+	case AttributeTypeCategorical:
+		return "categorical"
+	}
+	return "AttributeType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// ParseAttributeType parses the name of an attribute type as returned by
+// AttributeType.String.
+func ParseAttributeType(name string) (AttributeType, error) {
+	switch name {
+	case "numerical":
+		return AttributeTypeNumerical, nil
+	case "categorical":
+		return AttributeTypeCategorical, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownAttributeType, name)
+}
+
+// Schema maps the names of the columns read from a CSV file to their types.
+// It can be passed to NewDataSetFromCSV, and marshals to JSON as an object of
+// type names so an inferred schema can be saved and reused.
+type Schema map[string]AttributeType
+
+func (s Schema) MarshalJSON() ([]byte, error) {
+	names := make(map[string]string, len(s))
+	for name, typ := range s {
+		names[name] = typ.String()
+	}
+	return json.Marshal(names)
+}
+
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var names map[string]string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+
+	schema := make(Schema, len(names))
+	for name, typeName := range names {
+		typ, err := ParseAttributeType(typeName)
+		if err != nil {
+			return fmt.Errorf("attribute %s: %w", name, err)
+		}
+		schema[name] = typ
+	}
+	*s = schema
+	return nil
+}
+
+// DefaultInferSampleRows is the number of rows read to infer a schema when
+// InferOptions.SampleRows is zero.
+const DefaultInferSampleRows = 1000
+
+// InferOptions controls InferSchema and NewDataSetFromCSVInferred. The zero
+// value infers the type of every column from the first DefaultInferSampleRows
+// rows.
+type InferOptions struct {
+	// CSVOptions controls how values are read. Missing values are ignored
+	// when inferring types.
+	CSVOptions
+	// SampleRows is the number of rows read to infer types.
+	SampleRows int
+	// CategoricalThreshold is the largest number of distinct values in the
+	// sample for which a column of numbers is treated as categorical, such as
+	// a column of 0 and 1 flags. If zero, columns of numbers are always
+	// numerical.
+	CategoricalThreshold int
+	// MaxCategories is the largest number of distinct values in the sample
+	// that a categorical column may have. Columns with more, such as
+	// identifiers, are left out of the schema. If zero, there is no limit.
+	MaxCategories int
+	// Override gives the type of columns whose type should not be inferred.
+	// Overridden columns are never left out because of MaxCategories.
+	Override map[string]AttributeType
+	// Exclude lists columns left out of the schema.
+	Exclude []string
+}
+
+// InferSchema reads the header and up to opts.SampleRows rows from r and
+// returns a schema for its columns. A column is numerical if every value in
+// the sample that is not missing parses as a number, and categorical
+// otherwise, subject to the thresholds in opts. Columns with no values in the
+// sample are categorical.
+func InferSchema(r *csv.Reader, opts InferOptions) (Schema, error) {
+	header, err := readCSVHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	sample, err := readCSVRecords(r, opts.sampleRows())
+	if err != nil {
+		return nil, err
+	}
+	return inferSchema(header, sample, opts)
+}
+
+// NewDataSetFromCSVInferred is like NewDataSetFromCSVWithOptions but infers
+// the schema from the first rows of r as InferSchema does. It returns the
+// schema along with the data set.
+func NewDataSetFromCSVInferred(r *csv.Reader, opts InferOptions) (*DataSet, Schema, error) {
+	header, err := readCSVHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	sample, err := readCSVRecords(r, opts.sampleRows())
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, err := inferSchema(header, sample, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	read := csvRecords(r)
+	next := func() csvRecord {
+		if len(sample) > 0 {
+			record := sample[0]
+			sample = sample[1:]
+			return record
+		}
+		return read()
+	}
+	ds, err := newDataSetFromRecords(header, next, schema, opts.CSVOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ds, schema, nil
+}
+
+func (o InferOptions) sampleRows() int {
+	if o.SampleRows <= 0 {
+		return DefaultInferSampleRows
+	}
+	return o.SampleRows
+}
+
+// readCSVRecords reads up to n records from r. Records that could not be
+// parsed are returned with their error, to be handled by the caller.
+func readCSVRecords(r *csv.Reader, n int) ([]csvRecord, error) {
+	next := csvRecords(r)
+	records := make([]csvRecord, 0, n)
+	for len(records) < n {
+		record := next()
+		if errors.Is(record.err, io.EOF) {
+			break
+		} else if record.err != nil && record.line == 0 {
+			return nil, fmt.Errorf("error reading CSV row: %w", record.err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func inferSchema(header []string, sample []csvRecord, opts InferOptions) (Schema, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for name := range opts.Override {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("overridden attribute %s not found in CSV file", name)
+		}
+	}
+
+	excluded := make(map[string]bool, len(opts.Exclude))
+	for _, name := range opts.Exclude {
+		excluded[name] = true
+	}
+
+	schema := Schema{}
+	for name, i := range columns {
+		if excluded[name] {
+			continue
+		}
+		if typ, ok := opts.Override[name]; ok {
+			schema[name] = typ
+			continue
+		}
+
+		numerical := true
+		distinct := map[string]bool{}
+		for _, record := range sample {
+			if i >= len(record.fields) || opts.isMissing(record.fields[i]) {
+				continue
+			}
+			value := record.fields[i]
+			if numerical {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					numerical = false
+				}
+			}
+			distinct[strings.TrimSpace(value)] = true
+		}
+
+		switch {
+		case numerical && len(distinct) > 0 && len(distinct) > opts.CategoricalThreshold:
+			schema[name] = AttributeTypeNumerical
+		case opts.MaxCategories > 0 && len(distinct) > opts.MaxCategories:
+			// Left out, as it is most likely an identifier.
+		default:
+			schema[name] = AttributeTypeCategorical
+		}
+	}
+	return schema, nil
+}
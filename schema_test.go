@@ -0,0 +1,102 @@
+package goiforest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const inferInput = `id,Cost,Flag,Color,Empty
+a1,0.5,0,red,
+a2,NA,1,green,
+a3,1.5,1,red,
+a4,2,0,blue,`
+
+func TestInferSchema(t *testing.T) {
+	schema, err := InferSchema(csv.NewReader(strings.NewReader(inferInput)),
+		InferOptions{CSVOptions: CSVOptions{MissingTokens: DefaultMissingTokens}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := Schema{
+		"id":    AttributeTypeCategorical,
+		"Cost":  AttributeTypeNumerical,
+		"Flag":  AttributeTypeNumerical,
+		"Color": AttributeTypeCategorical,
+		"Empty": AttributeTypeCategorical,
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Expected %v, got %v", expected, schema)
+	}
+
+	schema, err = InferSchema(csv.NewReader(strings.NewReader(inferInput)), InferOptions{
+		CSVOptions:           CSVOptions{MissingTokens: DefaultMissingTokens},
+		CategoricalThreshold: 2,
+		MaxCategories:        3,
+		Override:             map[string]AttributeType{"Cost": AttributeTypeCategorical},
+		Exclude:              []string{"Empty"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = Schema{
+		"Cost":  AttributeTypeCategorical,
+		"Flag":  AttributeTypeCategorical,
+		"Color": AttributeTypeCategorical,
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Expected %v, got %v", expected, schema)
+	}
+
+	if _, err := InferSchema(csv.NewReader(strings.NewReader(inferInput)), InferOptions{
+		Override: map[string]AttributeType{"Unknown": AttributeTypeNumerical},
+	}); err == nil {
+		t.Errorf("Expected error overriding unknown attribute")
+	}
+}
+
+func TestNewDataSetFromCSVInferred(t *testing.T) {
+	ds, schema, err := NewDataSetFromCSVInferred(csv.NewReader(strings.NewReader(inferInput)), InferOptions{
+		CSVOptions: CSVOptions{MissingTokens: DefaultMissingTokens},
+		SampleRows: 2,
+		Exclude:    []string{"id", "Empty"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ds.Size != 4 || len(ds.Attributes) != 3 {
+		t.Fatalf("Expected 4 rows and 3 attributes, got %d and %d", ds.Size, len(ds.Attributes))
+	}
+	if schema["Cost"] != AttributeTypeNumerical {
+		t.Errorf("Expected Cost to be numerical, got %v", schema["Cost"])
+	}
+	cost := Attribute{Name: "Cost", Type: AttributeTypeNumerical}
+	if v := ds.Value(3, cost); v.Num != 2 {
+		t.Errorf("Expected rows after the sample to be read, got %v", v)
+	}
+}
+
+func TestSchemaJSON(t *testing.T) {
+	schema := Schema{"Cost": AttributeTypeNumerical, "Color": AttributeTypeCategorical}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(data) != `{"Color":"categorical","Cost":"numerical"}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+
+	var loaded Schema
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(schema, loaded) {
+		t.Errorf("Expected %v, got %v", schema, loaded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Cost":"float"}`), &loaded); err == nil {
+		t.Errorf("Expected error for unknown type")
+	}
+}
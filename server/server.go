@@ -0,0 +1,421 @@
+// Package server serves scores from an isolation forest over HTTP.
+//
+// All endpoints accept and return JSON. Data points are objects mapping each
+// of the forest's attribute names to a value, which may be a number, a string
+// or null. Null, the empty string and Options.MissingTokens are read as
+// missing values:
+//
+//	GET  /model        describes the forest
+//	POST /score        scores {"point": {...}}
+//	POST /score/batch  scores {"points": [{...}, ...]}
+//	POST /explain      scores and explains {"point": {...}}
+//	POST /reload       reloads the model file, if the server has one
+//
+// Errors are returned as {"error": "..."} with a 4xx or 5xx status.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mikemherron/goiforest"
+)
+
+// Options controls a Server. The zero value uses the default limits.
+type Options struct {
+	// MissingTokens lists string values read as missing, in addition to
+	// null and the empty string.
+	MissingTokens []string
+	// MaxBodyBytes limits the size of request bodies. If zero, it is
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// MaxBatchSize limits the number of points scored by one batch request.
+	// If zero, it is DefaultMaxBatchSize.
+	MaxBatchSize int
+}
+
+const (
+	DefaultMaxBodyBytes = 10 << 20
+	DefaultMaxBatchSize = 10000
+)
+
+// Server is an http.Handler scoring data points with a forest. The forest can
+// be replaced while the server is running; requests in flight finish with the
+// forest they started with.
+type Server struct {
+	forest   atomic.Pointer[model]
+	path     string
+	opts     Options
+	mux      *http.ServeMux
+	reloadMu sync.Mutex
+}
+
+// model is a forest with the time it was loaded and, if it was loaded from a
+// file, the modification time of the file.
+type model struct {
+	forest   *goiforest.IsolationForest
+	loadedAt time.Time
+	modTime  time.Time
+}
+
+// New returns a server scoring with forest.
+func New(forest *goiforest.IsolationForest, opts Options) *Server {
+	s := newServer(opts)
+	s.forest.Store(&model{forest: forest, loadedAt: time.Now()})
+	return s
+}
+
+// NewFromFile returns a server scoring with the model saved at path, which
+// Reload loads again.
+func NewFromFile(path string, opts Options) (*Server, error) {
+	s := newServer(opts)
+	s.path = path
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newServer(opts Options) *Server {
+	if opts.MaxBodyBytes == 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.MaxBatchSize == 0 {
+		opts.MaxBatchSize = DefaultMaxBatchSize
+	}
+
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /model", s.handleModel)
+	s.mux.HandleFunc("POST /score", s.handleScore)
+	s.mux.HandleFunc("POST /score/batch", s.handleBatch)
+	s.mux.HandleFunc("POST /explain", s.handleExplain)
+	s.mux.HandleFunc("POST /reload", s.handleReload)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Forest returns the forest currently used for scoring.
+func (s *Server) Forest() *goiforest.IsolationForest {
+	return s.forest.Load().forest
+}
+
+// SetForest replaces the forest used for scoring.
+func (s *Server) SetForest(forest *goiforest.IsolationForest) {
+	s.forest.Store(&model{forest: forest, loadedAt: time.Now()})
+}
+
+// Reload loads the server's model file and, if it loads successfully, starts
+// scoring with it. If the file cannot be loaded the current forest is kept.
+func (s *Server) Reload() error {
+	if s.path == "" {
+		return fmt.Errorf("server has no model file")
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	forest, err := goiforest.Load(file)
+	if err != nil {
+		return fmt.Errorf("error loading model %s: %w", s.path, err)
+	}
+
+	s.forest.Store(&model{forest: forest, loadedAt: time.Now(), modTime: info.ModTime()})
+	return nil
+}
+
+// Watch reloads the model file whenever its modification time changes,
+// checking every interval until ctx is done. Errors reloading are passed to
+// onError, which may be nil, and the current forest is kept. Watch returns
+// immediately if the server has no model file.
+func (s *Server) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	if s.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(s.path)
+		if err == nil && info.ModTime().Equal(s.forest.Load().modTime) {
+			continue
+		}
+		if err == nil {
+			err = s.Reload()
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+type attributeJSON struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type modelResponse struct {
+	Attributes       []attributeJSON `json:"attributes"`
+	Trees            int             `json:"trees"`
+	SampleSize       int             `json:"sample_size"`
+	MaxDepth         int             `json:"max_depth"`
+	Seed             int64           `json:"seed"`
+	Extended         bool            `json:"extended"`
+	UnseenCategories string          `json:"unseen_categories"`
+	Contamination    float64         `json:"contamination"`
+	Threshold        float64         `json:"threshold"`
+	LoadedAt         time.Time       `json:"loaded_at"`
+}
+
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	m := s.forest.Load()
+	config := m.forest.Config()
+	resp := modelResponse{
+		Trees:            len(m.forest.Trees),
+		SampleSize:       config.SampleSize,
+		MaxDepth:         config.MaxDepth,
+		Seed:             config.Seed,
+		Extended:         config.Extended,
+		UnseenCategories: config.UnseenCategories.String(),
+		Contamination:    config.Contamination,
+		Threshold:        m.forest.Threshold(),
+		LoadedAt:         m.loadedAt,
+	}
+	for _, attr := range m.forest.Attributes() {
+		resp.Attributes = append(resp.Attributes, attributeJSON{Name: attr.Name, Type: attr.Type.String()})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type pointRequest struct {
+	Point map[string]any `json:"point"`
+}
+
+type batchRequest struct {
+	Points []map[string]any `json:"points"`
+}
+
+type scoreResponse struct {
+	Score             float64 `json:"score"`
+	Anomaly           bool    `json:"anomaly"`
+	Decision          float64 `json:"decision"`
+	AveragePathLength float64 `json:"average_path_length"`
+	// Unseen lists the attributes with categories not seen in training.
+	Unseen []string `json:"unseen,omitempty"`
+}
+
+type batchResponse struct {
+	Results []scoreResponse `json:"results"`
+}
+
+type contributionJSON struct {
+	Attribute    string  `json:"attribute"`
+	Contribution float64 `json:"contribution"`
+}
+
+type explainResponse struct {
+	scoreResponse
+	Contributions []contributionJSON `json:"contributions"`
+}
+
+func (s *Server) handleScore(w http.ResponseWriter, r *http.Request) {
+	var req pointRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	forest := s.Forest()
+	result, err := s.score(forest, req.Point)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newScoreResponse(forest, result))
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if len(req.Points) > s.opts.MaxBatchSize {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Errorf("batch of %d points exceeds the limit of %d", len(req.Points), s.opts.MaxBatchSize))
+		return
+	}
+
+	forest := s.Forest()
+	resp := batchResponse{Results: make([]scoreResponse, len(req.Points))}
+	for i, point := range req.Points {
+		result, err := s.score(forest, point)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("point %d: %w", i, err))
+			return
+		}
+		resp.Results[i] = newScoreResponse(forest, result)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	var req pointRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	forest := s.Forest()
+	dataPoint, err := s.dataPoint(forest, req.Point)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	explanation, err := forest.Explain(dataPoint, s.scoreOptions())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := explainResponse{
+		scoreResponse: newScoreResponse(forest, explanation.ScoreResult),
+		Contributions: make([]contributionJSON, len(explanation.Ranked)),
+	}
+	for i, c := range explanation.Ranked {
+		resp.Contributions[i] = contributionJSON{Attribute: c.Attribute.Name, Contribution: c.Contribution}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.path == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("server has no model file"))
+		return
+	}
+	if err := s.Reload(); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.handleModel(w, r)
+}
+
+func (s *Server) score(forest *goiforest.IsolationForest, point map[string]any) (goiforest.ScoreResult, error) {
+	dataPoint, err := s.dataPoint(forest, point)
+	if err != nil {
+		return goiforest.ScoreResult{}, err
+	}
+	return forest.ScoreWithOptions(dataPoint, s.scoreOptions())
+}
+
+// missingValue stands in for null values, which are always missing.
+const missingValue = ""
+
+func (s *Server) scoreOptions() goiforest.ScoreOptions {
+	return goiforest.ScoreOptions{MissingTokens: append([]string{missingValue}, s.opts.MissingTokens...)}
+}
+
+// dataPoint converts a JSON data point to the form accepted by the forest,
+// checking it has exactly the forest's attributes.
+func (s *Server) dataPoint(forest *goiforest.IsolationForest, point map[string]any) (map[string]string, error) {
+	if point == nil {
+		return nil, fmt.Errorf("no data point given")
+	}
+
+	attributes := forest.Attributes()
+	dataPoint := make(map[string]string, len(attributes))
+	for _, attr := range attributes {
+		value, ok := point[attr.Name]
+		if !ok {
+			return nil, &goiforest.MissingAttributeError{Attribute: attr.Name}
+		}
+		switch v := value.(type) {
+		case nil:
+			dataPoint[attr.Name] = missingValue
+		case string:
+			dataPoint[attr.Name] = v
+		case float64:
+			dataPoint[attr.Name] = strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			dataPoint[attr.Name] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("attribute %s has unsupported value %v", attr.Name, value)
+		}
+	}
+	if len(point) > len(attributes) {
+		for name := range point {
+			if _, ok := dataPoint[name]; !ok {
+				return nil, fmt.Errorf("unknown attribute %s", name)
+			}
+		}
+	}
+	return dataPoint, nil
+}
+
+func newScoreResponse(forest *goiforest.IsolationForest, result goiforest.ScoreResult) scoreResponse {
+	resp := scoreResponse{
+		Score:             result.Score,
+		Anomaly:           result.Score > forest.Threshold(),
+		Decision:          result.Score - forest.Threshold(),
+		AveragePathLength: result.AveragePathLength,
+	}
+	for _, attr := range result.Unseen {
+		resp.Unseen = append(resp.Unseen, attr.Name)
+	}
+	return resp
+}
+
+// decode reads the JSON request body into v, writing an error response and
+// returning false if it cannot.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
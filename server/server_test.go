@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikemherron/goiforest"
+)
+
+func testForest(t *testing.T, seed int64) *goiforest.IsolationForest {
+	x := goiforest.Attribute{Name: "X", Type: goiforest.AttributeTypeNumerical}
+	color := goiforest.Attribute{Name: "Color", Type: goiforest.AttributeTypeCategorical}
+	ds := goiforest.NewDataSet()
+	ds.AddAttribute(x)
+	ds.AddAttribute(color)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		ds.AddRow(map[goiforest.Attribute]goiforest.AttributeValue{
+			x:     {Num: r.NormFloat64()},
+			color: {Str: []string{"red", "blue"}[r.Intn(2)]},
+		})
+	}
+
+	forest, err := goiforest.BuildForestWithConfig(ds, goiforest.ForestConfig{NumTrees: 20, SampleSize: 128, Seed: seed})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return forest
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, v any) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("Error decoding %s response %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestScore(t *testing.T) {
+	forest := testForest(t, 3)
+	s := New(forest, Options{MissingTokens: []string{"NA"}})
+
+	var resp scoreResponse
+	if code := do(t, s, "POST", "/score", `{"point": {"X": 6, "Color": "red"}}`, &resp); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	expected := forest.Score(map[string]string{"X": "6", "Color": "red"})
+	if resp.Score != expected.Score || resp.Anomaly != (expected.Score > forest.Threshold()) {
+		t.Errorf("Expected score %f, got %+v", expected.Score, resp)
+	}
+
+	var unseen scoreResponse
+	if code := do(t, s, "POST", "/score", `{"point": {"X": 0, "Color": "purple"}}`, &unseen); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if len(unseen.Unseen) != 1 || unseen.Unseen[0] != "Color" {
+		t.Errorf("Expected Color to be reported unseen, got %+v", unseen)
+	}
+
+	var batch batchResponse
+	body := `{"points": [{"X": 0.1, "Color": "blue"}, {"X": null, "Color": "NA"}, {"X": "1.5", "Color": "red"}]}`
+	if code := do(t, s, "POST", "/score/batch", body, &batch); code != http.StatusOK || len(batch.Results) != 3 {
+		t.Fatalf("Expected 3 results, got status %d and %+v", code, batch)
+	}
+
+	var explain explainResponse
+	if code := do(t, s, "POST", "/explain", `{"point": {"X": 6, "Color": "red"}}`, &explain); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if explain.Score != expected.Score || len(explain.Contributions) != 2 || explain.Contributions[0].Attribute != "X" {
+		t.Errorf("Expected X to contribute most, got %+v", explain)
+	}
+
+	var model modelResponse
+	if code := do(t, s, "GET", "/model", "", &model); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+	if model.Trees != 20 || model.Seed != 3 || len(model.Attributes) != 2 || model.Attributes[1].Type != "categorical" {
+		t.Errorf("Unexpected model %+v", model)
+	}
+}
+
+func TestScoreInvalid(t *testing.T) {
+	s := New(testForest(t, 3), Options{MaxBatchSize: 1})
+
+	for _, test := range []struct {
+		path, body string
+		code       int
+	}{
+		{"/score", `{"point": {"X": 1}}`, http.StatusBadRequest},
+		{"/score", `{"point": {"X": 1, "Color": "red", "Y": 2}}`, http.StatusBadRequest},
+		{"/score", `{"point": {"X": "one", "Color": "red"}}`, http.StatusBadRequest},
+		{"/score", `{"point": {"X": [1], "Color": "red"}}`, http.StatusBadRequest},
+		{"/score", `{"points": []}`, http.StatusBadRequest},
+		{"/score", `not json`, http.StatusBadRequest},
+		{"/score/batch", `{"points": [{"X": 1, "Color": "red"}, {"X": 2, "Color": "red"}]}`, http.StatusRequestEntityTooLarge},
+		{"/reload", ``, http.StatusNotFound},
+	} {
+		var resp errorResponse
+		if code := do(t, s, "POST", test.path, test.body, &resp); code != test.code || resp.Error == "" {
+			t.Errorf("%s %s: expected status %d with an error, got %d %+v", test.path, test.body, test.code, code, resp)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.bin")
+	save := func(forest *goiforest.IsolationForest) {
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := forest.Save(file); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+
+	save(testForest(t, 3))
+	s, err := NewFromFile(path, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	save(testForest(t, 5))
+	var model modelResponse
+	if code := do(t, s, "POST", "/reload", "", &model); code != http.StatusOK || model.Seed != 5 {
+		t.Errorf("Expected reloaded model with seed 5, got %d %+v", code, model)
+	}
+
+	if err := os.WriteFile(path, []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reload(); err == nil {
+		t.Errorf("Expected error reloading a corrupt model")
+	}
+	if s.Forest().Config().Seed != 5 {
+		t.Errorf("Expected the current forest to be kept after a failed reload")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	go s.Watch(ctx, 10*time.Millisecond, func(err error) { errs <- err })
+	if err := <-errs; err == nil {
+		t.Errorf("Expected watch to report the corrupt model")
+	}
+
+	save(testForest(t, 7))
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Forest().Config().Seed != 7 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s.Forest().Config().Seed != 7 {
+		t.Errorf("Expected watch to load the new model")
+	}
+}
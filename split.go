@@ -0,0 +1,480 @@
+package goiforest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// RowValues gives access to the values of a single row by the position of
+// their attribute, which for split conditions is the position in the forest's
+// attributes.
+type RowValues interface {
+	Value(i int) AttributeValue
+}
+
+// Condition decides which child of a node a row follows. Rows that match go
+// left. Matches is only called if Missing returns false: while building a
+// tree, rows missing a value the condition depends on follow the child that
+// most training rows followed, and when scoring, a data point missing such a
+// value follows both children, with their path lengths weighted by the number
+// of training rows that followed each. Conditions refer to attributes by their
+// position in the forest's attributes, which are those of the data set passed
+// to SplitStrategy.Split.
+//
+// Forests with conditions other than those returned by NewSplitCondition and
+// the built in strategies can only be saved and loaded if the condition's
+// type is registered with RegisterCondition.
+type Condition interface {
+	Matches(row RowValues) bool
+	Missing(row RowValues) bool
+	// Attributes returns the attributes the condition depends on, which are
+	// credited when explaining scores and measuring importance.
+	Attributes() []Attribute
+	// String describes the condition, or its inverse for the right branch.
+	String(inverse bool) string
+}
+
+// describer is implemented by the built in conditions to fill in the
+// condition specific fields of a trace step. Steps through other conditions
+// only have Attributes set, and Attribute and Value if there is only one.
+type describer interface {
+	describe(step *TraceStep, row RowValues)
+}
+
+// NewSplitCondition returns the condition used by the standard algorithm on
+// the attribute at position index. Rows match if their value of a numerical
+// attribute is at least value.Num, or if their value of a categorical
+// attribute equals value.Str. Strategies can return it to vary how split
+// values are chosen without registering a condition type.
+func NewSplitCondition(attribute Attribute, index int, value AttributeValue) Condition {
+	return &splitCondition{attribute: attribute, index: index, strVal: value.Str, numVal: value.Num}
+}
+
+// SplitStrategy chooses the condition each node of a standard forest splits
+// its data set on. Trees are built concurrently, so a strategy must be safe
+// for use by several goroutines, and should draw any random numbers from the
+// r it is passed so forests built with the same seed are identical.
+type SplitStrategy interface {
+	// Split returns a condition on the attributes of d at positions
+	// candidates, or ErrNotSplittable if d cannot be split further. If every
+	// row follows the same branch of a condition on a single attribute, the
+	// attribute is not a candidate again further down the tree. Any other
+	// error stops the forest being built and is returned by
+	// BuildForestWithConfig or AddTrees.
+	Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error)
+}
+
+// RandomSplit implements the standard algorithm and is the default strategy.
+// It splits on a random attribute, at a threshold drawn uniformly from the
+// range of a numerical attribute, or on equality with the value of a
+// categorical attribute in a random row, which favours frequent categories.
+type RandomSplit struct {
+	// UniformCategories draws the category of a categorical split uniformly
+	// from the distinct values present, so rare categories are as likely to
+	// be isolated as frequent ones.
+	UniformCategories bool
+	// CategorySets splits categorical attributes on membership of a random
+	// subset of the distinct values present rather than on equality with one
+	// of them, so each split separates many categories. This keeps trees
+	// shallow for attributes with many categories, such as identifiers.
+	// Categories not seen in training follow the branch fewer training rows
+	// followed.
+	CategorySets bool
+}
+
+func (s RandomSplit) Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error) {
+	if !s.CategorySets {
+		c, err := d.randomSplitCondition(candidates, r, s.UniformCategories)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	if len(candidates) == 0 {
+		return nil, ErrNotSplittable
+	}
+	idx := candidates[r.Intn(len(candidates))]
+	if d.Attributes[idx].Type == AttributeTypeCategorical {
+		if c, ok := d.randomCategorySet(idx, r); ok {
+			return c, nil
+		}
+	}
+	return d.randomSplitConditionOn(idx, r, s.UniformCategories), nil
+}
+
+// Defaults for the zero fields of SCiForestSplit.
+const (
+	DefaultSCiForestCandidates = 10
+	DefaultSCiForestAttributes = 2
+)
+
+// SCiForestSplit chooses the split of each node from several random
+// candidates by how well they separate the data, following the SCiForest
+// algorithm of Liu, Ting and Zhou. This isolates clustered anomalies, which
+// random splits rarely separate from the normal data, in fewer splits.
+//
+// Each candidate starts from a random attribute. A numerical attribute gives
+// a hyperplane through it and other random numerical attributes, with random
+// weights scaled by each attribute's standard deviation, split at the point
+// that most reduces the mean standard deviation of the projected values on
+// each side. A categorical attribute is split on the category whose removal
+// most reduces the Gini impurity of the rest. The candidate with the largest
+// relative reduction is used. Missing values are ignored.
+type SCiForestSplit struct {
+	// Candidates is the number of candidate splits compared at each node,
+	// DefaultSCiForestCandidates if zero.
+	Candidates int
+	// Attributes is the largest number of numerical attributes a hyperplane
+	// spans, DefaultSCiForestAttributes if zero. One gives splits on a single
+	// attribute.
+	Attributes int
+}
+
+func (s SCiForestSplit) validate() error {
+	if s.Candidates < 0 {
+		return fmt.Errorf("SCiForest candidates must not be negative, got %d", s.Candidates)
+	}
+	if s.Attributes < 0 {
+		return fmt.Errorf("SCiForest attributes must not be negative, got %d", s.Attributes)
+	}
+	return nil
+}
+
+func (s SCiForestSplit) Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error) {
+	numCandidates := s.Candidates
+	if numCandidates == 0 {
+		numCandidates = DefaultSCiForestCandidates
+	}
+	numAttributes := s.Attributes
+	if numAttributes == 0 {
+		numAttributes = DefaultSCiForestAttributes
+	}
+
+	// Only attributes with at least two distinct values can be split.
+	var splittable, numerical []int
+	stdDevs := make(map[int]float64)
+	for _, idx := range candidates {
+		if d.Attributes[idx].Type == AttributeTypeNumerical {
+			if stdDev := d.numericStdDev(idx); stdDev > 0 {
+				stdDevs[idx] = stdDev
+				splittable = append(splittable, idx)
+				numerical = append(numerical, idx)
+			}
+		} else if categories, _ := d.categoryCounts(idx); len(categories) > 1 {
+			splittable = append(splittable, idx)
+		}
+	}
+	if len(splittable) == 0 {
+		return nil, ErrNotSplittable
+	}
+
+	var best Condition
+	bestGain := math.Inf(-1)
+	for i := 0; i < numCandidates; i++ {
+		idx := splittable[r.Intn(len(splittable))]
+
+		var c Condition
+		var gain float64
+		var ok bool
+		if d.Attributes[idx].Type == AttributeTypeNumerical {
+			indices := []int{idx}
+			for _, other := range r.Perm(len(numerical)) {
+				if len(indices) == numAttributes {
+					break
+				}
+				if numerical[other] != idx {
+					indices = append(indices, numerical[other])
+				}
+			}
+			c, gain, ok = sciForestHyperplane(d, indices, stdDevs, r)
+		} else {
+			c, gain, ok = sciForestCategory(d, idx)
+		}
+
+		if ok && gain > bestGain {
+			best, bestGain = c, gain
+		}
+	}
+
+	if best == nil {
+		return nil, ErrNotSplittable
+	}
+	return best, nil
+}
+
+// sciForestHyperplane returns a hyperplane over the numerical attributes at
+// positions indices with random weights, split where the mean standard
+// deviation of the projected values on each side is smallest, and the
+// relative reduction in standard deviation. It reports false if the projected
+// values cannot be split.
+func sciForestHyperplane(d *DataSet, indices []int, stdDevs map[int]float64, r *rand.Rand) (Condition, float64, bool) {
+	c := &hyperplaneCondition{indices: indices}
+	for _, idx := range indices {
+		c.attributes = append(c.attributes, d.Attributes[idx])
+		c.normal = append(c.normal, r.NormFloat64()/stdDevs[idx])
+	}
+
+	projections := make([]float64, 0, d.Size)
+	row := &dataSetRow{d: d}
+	for i := 0; i < d.Size; i++ {
+		row.row = i
+		if !c.Missing(row) {
+			projections = append(projections, c.project(row))
+		}
+	}
+	sort.Float64s(projections)
+
+	n := len(projections)
+	var total, totalSq float64
+	for _, p := range projections {
+		total += p
+		totalSq += p * p
+	}
+	stdDev := sumsStdDev(total, totalSq, n)
+	if n < 2 || stdDev == 0 {
+		return nil, 0, false
+	}
+
+	bestGain := math.Inf(-1)
+	var sum, sumSq float64
+	for k := 1; k < n; k++ {
+		sum += projections[k-1]
+		sumSq += projections[k-1] * projections[k-1]
+		if projections[k] == projections[k-1] {
+			continue
+		}
+		left := sumsStdDev(sum, sumSq, k)
+		right := sumsStdDev(total-sum, totalSq-sumSq, n-k)
+		if gain := (stdDev - (left+right)/2) / stdDev; gain > bestGain {
+			bestGain = gain
+			c.offset = (projections[k-1] + projections[k]) / 2
+		}
+	}
+	if math.IsInf(bestGain, -1) {
+		return nil, 0, false
+	}
+	return c, bestGain, true
+}
+
+// sciForestCategory returns a split of the categorical attribute at position
+// idx on the category whose removal most reduces the Gini impurity of the
+// rest, and the relative reduction in impurity. It reports false if there are
+// fewer than two categories.
+func sciForestCategory(d *DataSet, idx int) (Condition, float64, bool) {
+	categories, counts := d.categoryCounts(idx)
+	if len(categories) < 2 {
+		return nil, 0, false
+	}
+
+	var n, sumSq float64
+	for _, count := range counts {
+		n += float64(count)
+		sumSq += float64(count) * float64(count)
+	}
+	impurity := 1 - sumSq/(n*n)
+
+	best := 0
+	bestGain := math.Inf(-1)
+	for i, count := range counts {
+		c := float64(count)
+		// The removed category is pure, so only the rest adds impurity.
+		rest := 1 - (sumSq-c*c)/((n-c)*(n-c))
+		if gain := (impurity - rest/2) / impurity; gain > bestGain {
+			best, bestGain = i, gain
+		}
+	}
+
+	return &splitCondition{attribute: d.Attributes[idx], index: idx, strVal: categories[best]}, bestGain, true
+}
+
+// sumsStdDev returns the standard deviation of n values from their sum and
+// sum of squares.
+func sumsStdDev(sum, sumSq float64, n int) float64 {
+	mean := sum / float64(n)
+	return math.Sqrt(math.Max(0, sumSq/float64(n)-mean*mean))
+}
+
+// numericStdDev returns the standard deviation of the numerical attribute at
+// position idx, ignoring missing values.
+func (d *DataSet) numericStdDev(idx int) float64 {
+	col := d.columns[idx]
+	var sum, sumSq float64
+	n := 0
+	for i := 0; i < d.Size; i++ {
+		row := d.storeRow(i)
+		if col.isMissing(row) {
+			continue
+		}
+		sum += col.num[row]
+		sumSq += col.num[row] * col.num[row]
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sumsStdDev(sum, sumSq, n)
+}
+
+// hyperplaneSplitter implements the Extended Isolation Forest algorithm,
+// splitting on a random hyperplane through a random point within the range of
+// the data set. Only extensionLevel+1 randomly chosen candidate attributes, or
+// every candidate if there are fewer, have a non zero component in the
+// hyperplane's normal.
+type hyperplaneSplitter struct {
+	extensionLevel int
+}
+
+func (s hyperplaneSplitter) Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error) {
+	mins := make([]float64, len(d.Attributes))
+	maxs := make([]float64, len(d.Attributes))
+	splittable := false
+	for _, i := range candidates {
+		mins[i], maxs[i] = d.numericRange(i)
+		if maxs[i] > mins[i] {
+			splittable = true
+		}
+	}
+
+	if !splittable {
+		return nil, ErrNotSplittable
+	}
+
+	dims := s.extensionLevel + 1
+	if dims > len(candidates) {
+		dims = len(candidates)
+	}
+	c := &hyperplaneCondition{}
+	for _, j := range r.Perm(len(candidates))[:dims] {
+		i := candidates[j]
+		n := r.NormFloat64()
+		p := mins[i] + (r.Float64() * (maxs[i] - mins[i]))
+		c.attributes = append(c.attributes, d.Attributes[i])
+		c.indices = append(c.indices, i)
+		c.normal = append(c.normal, n)
+		c.offset += n * p
+	}
+
+	return c, nil
+}
+
+// hyperplaneCondition splits on a hyperplane over the attributes at positions
+// indices in the attributes of the data set or forest it applies to.
+type hyperplaneCondition struct {
+	attributes []Attribute
+	indices    []int
+	normal     []float64
+	offset     float64
+}
+
+func (h *hyperplaneCondition) project(row RowValues) float64 {
+	var dot float64
+	for i, idx := range h.indices {
+		dot += h.normal[i] * row.Value(idx).Num
+	}
+	return dot
+}
+
+func (h *hyperplaneCondition) Matches(row RowValues) bool {
+	return h.project(row) >= h.offset
+}
+
+func (h *hyperplaneCondition) Attributes() []Attribute {
+	return h.attributes
+}
+
+func (h *hyperplaneCondition) Missing(row RowValues) bool {
+	for _, idx := range h.indices {
+		if row.Value(idx).Missing {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *hyperplaneCondition) describe(step *TraceStep, row RowValues) {
+	step.Attributes = h.attributes
+	step.Normal = h.normal
+	step.Value = AttributeValue{Num: h.project(row)}
+	step.Threshold = h.offset
+}
+
+func (h *hyperplaneCondition) String(inverse bool) string {
+	terms := make([]string, len(h.attributes))
+	for i, attr := range h.attributes {
+		terms[i] = fmt.Sprintf("%f*%s", h.normal[i], attr.Name)
+	}
+
+	op := ">="
+	if inverse {
+		op = "<"
+	}
+	return fmt.Sprintf("%s %s %f", strings.Join(terms, " + "), op, h.offset)
+}
+
+// setCondition splits a categorical attribute on membership of the left set
+// of categories. Categories in neither set were not seen when the condition
+// was built, and follow the left branch if unseenLeft is set.
+type setCondition struct {
+	attribute  Attribute
+	index      int
+	left       []string
+	right      []string
+	sides      map[string]bool
+	unseenLeft bool
+}
+
+// newSetCondition returns a condition matching the categories in left. The
+// sets are sorted so the condition does not depend on the order of rows.
+func newSetCondition(attribute Attribute, index int, left, right []string, unseenLeft bool) *setCondition {
+	sort.Strings(left)
+	sort.Strings(right)
+	c := &setCondition{
+		attribute:  attribute,
+		index:      index,
+		left:       left,
+		right:      right,
+		sides:      make(map[string]bool, len(left)+len(right)),
+		unseenLeft: unseenLeft,
+	}
+	for _, category := range left {
+		c.sides[category] = true
+	}
+	for _, category := range right {
+		c.sides[category] = false
+	}
+	return c
+}
+
+func (c *setCondition) Matches(row RowValues) bool {
+	left, seen := c.sides[row.Value(c.index).Str]
+	if !seen {
+		return c.unseenLeft
+	}
+	return left
+}
+
+func (c *setCondition) Missing(row RowValues) bool {
+	return row.Value(c.index).Missing
+}
+
+func (c *setCondition) Attributes() []Attribute {
+	return []Attribute{c.attribute}
+}
+
+func (c *setCondition) describe(step *TraceStep, row RowValues) {
+	step.Attribute = c.attribute
+	step.Value = row.Value(c.index)
+	step.Categories = c.left
+}
+
+func (c *setCondition) String(inverse bool) string {
+	op := "in"
+	if inverse {
+		op = "not in"
+	}
+	return fmt.Sprintf("%s %s {%s}", c.attribute.Name, op, strings.Join(c.left, ", "))
+}
@@ -0,0 +1,438 @@
+package goiforest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// clusteredDataSet returns normally distributed rows with a tight cluster of
+// anomalies far from the rest.
+func clusteredDataSet() *DataSet {
+	x := Attribute{Name: "X", Type: AttributeTypeNumerical}
+	y := Attribute{Name: "Y", Type: AttributeTypeNumerical}
+	ds := NewDataSet()
+	ds.AddAttribute(x)
+	ds.AddAttribute(y)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		ds.AddRow(map[Attribute]AttributeValue{x: {Num: r.NormFloat64()}, y: {Num: r.NormFloat64()}})
+	}
+	for i := 0; i < 50; i++ {
+		ds.AddRow(map[Attribute]AttributeValue{
+			x: {Num: 4 + r.NormFloat64()*0.05},
+			y: {Num: 4 + r.NormFloat64()*0.05},
+		})
+	}
+	return ds
+}
+
+func TestSCiForestClusteredAnomalies(t *testing.T) {
+	ds := clusteredDataSet()
+	cluster := map[string]string{"X": "4", "Y": "4"}
+
+	scores := map[string]float64{}
+	for name, strategy := range map[string]SplitStrategy{"random": nil, "sciforest": SCiForestSplit{}} {
+		forest, err := BuildForestWithConfig(ds, ForestConfig{
+			NumTrees: 100, SampleSize: 256, Seed: 7, SplitStrategy: strategy,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		scores[name] = forest.Score(cluster).Score
+
+		if inlier := forest.Score(map[string]string{"X": "0", "Y": "0"}).Score; inlier >= scores[name] {
+			t.Errorf("%s: expected cluster score %f to exceed inlier score %f", name, scores[name], inlier)
+		}
+	}
+
+	if scores["sciforest"] <= scores["random"] {
+		t.Errorf("Expected SCiForest cluster score %f to exceed random split score %f",
+			scores["sciforest"], scores["random"])
+	}
+}
+
+func TestSCiForestMixedAttributes(t *testing.T) {
+	ds := testDataSet(300)
+	config := ForestConfig{NumTrees: 20, SampleSize: 128, Seed: 5, SplitStrategy: SCiForestSplit{Attributes: 1}}
+
+	forest, err := BuildForestWithConfig(ds, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	again, err := BuildForestWithConfig(ds, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	categorical := 0
+	for _, tree := range forest.Trees {
+		tree.Root.walk(0, func(node *IsolationTreeNode, _ int) {
+			switch split := node.split.(type) {
+			case *splitCondition:
+				categorical++
+			case *hyperplaneCondition:
+				if len(split.indices) != 1 {
+					t.Errorf("Expected hyperplanes over a single attribute, got %v", split.String(false))
+				}
+			}
+		})
+	}
+	if categorical == 0 {
+		t.Errorf("Expected some splits on the categorical attribute")
+	}
+
+	row := map[string]string{"X": "3", "Y": "-2", "Color": "red"}
+	if forest.Score(row).Score != again.Score(row).Score {
+		t.Errorf("Expected forests built with the same seed to score identically")
+	}
+}
+
+func TestSCiForestCategory(t *testing.T) {
+	color := Attribute{Name: "Color", Type: AttributeTypeCategorical}
+	ds := NewDataSet()
+	ds.AddAttribute(color)
+	for _, c := range []struct {
+		value string
+		count int
+	}{{"red", 90}, {"green", 8}, {"blue", 2}} {
+		for i := 0; i < c.count; i++ {
+			ds.AddRow(map[Attribute]AttributeValue{color: {Str: c.value}})
+		}
+	}
+
+	// Removing green leaves the purest rest, while removing the majority
+	// leaves the most impure.
+	split, _, ok := sciForestCategory(ds, 0)
+	if !ok || split.(*splitCondition).strVal != "green" {
+		t.Errorf("Expected split isolating green, got %v", split)
+	}
+}
+
+func TestUniformCategories(t *testing.T) {
+	color := Attribute{Name: "Color", Type: AttributeTypeCategorical}
+	ds := NewDataSet()
+	ds.AddAttribute(color)
+	for i := 0; i < 100; i++ {
+		value := "common"
+		if i%50 == 0 {
+			value = fmt.Sprintf("rare%d", i)
+		}
+		ds.AddRow(map[Attribute]AttributeValue{color: {Str: value}})
+	}
+
+	r := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		split, err := RandomSplit{UniformCategories: true}.Split(ds, []int{0}, r)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		counts[split.(*splitCondition).strVal]++
+	}
+
+	for _, value := range []string{"common", "rare0", "rare50"} {
+		if counts[value] < 800 || counts[value] > 1200 {
+			t.Errorf("Expected each category to be chosen about 1000 times, got %v", counts)
+			break
+		}
+	}
+}
+
+func TestSplitStrategyInvalid(t *testing.T) {
+	ds := testDataSet(100)
+
+	cases := []ForestConfig{
+		{NumTrees: 10, SampleSize: 50, SplitStrategy: SCiForestSplit{Candidates: -1}},
+		{NumTrees: 10, SampleSize: 50, SplitStrategy: SCiForestSplit{Attributes: -1}},
+		{NumTrees: 10, SampleSize: 50, Attributes: []string{"X", "Y"}, Extended: true, SplitStrategy: RandomSplit{}},
+	}
+
+	for _, c := range cases {
+		if _, err := BuildForestWithConfig(ds, c); err == nil {
+			t.Errorf("Expected error for config %+v", c)
+		}
+	}
+}
+
+// logThresholdSplit splits positive numerical attributes at thresholds drawn
+// uniformly on a log scale, using the standard condition.
+type logThresholdSplit struct{}
+
+func (logThresholdSplit) Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error) {
+	if len(candidates) == 0 {
+		return nil, ErrNotSplittable
+	}
+	idx := candidates[r.Intn(len(candidates))]
+	attr := d.Attributes[idx]
+	min, max := math.Inf(1), math.Inf(-1)
+	for i := 0; i < d.Size; i++ {
+		v := d.Value(i, attr).Num
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	threshold := math.Exp(math.Log(min) + r.Float64()*(math.Log(max)-math.Log(min)))
+	return NewSplitCondition(attr, idx, AttributeValue{Num: threshold}), nil
+}
+
+// ratioCondition matches rows where the ratio of two attributes is at least
+// Threshold.
+type ratioCondition struct {
+	Numerator   Attribute
+	Denominator Attribute
+	Indices     [2]int
+	Threshold   float64
+}
+
+func (c *ratioCondition) Matches(row RowValues) bool {
+	return row.Value(c.Indices[0]).Num/row.Value(c.Indices[1]).Num >= c.Threshold
+}
+
+func (c *ratioCondition) Missing(row RowValues) bool {
+	return row.Value(c.Indices[0]).Missing || row.Value(c.Indices[1]).Missing
+}
+
+func (c *ratioCondition) Attributes() []Attribute {
+	return []Attribute{c.Numerator, c.Denominator}
+}
+
+func (c *ratioCondition) String(inverse bool) string {
+	op := ">="
+	if inverse {
+		op = "<"
+	}
+	return fmt.Sprintf("%s/%s %s %f", c.Numerator.Name, c.Denominator.Name, op, c.Threshold)
+}
+
+// ratioSplit splits on the ratio of the first two attributes at a random
+// threshold between 0 and 2.
+type ratioSplit struct{}
+
+func (ratioSplit) Split(d *DataSet, _ []int, r *rand.Rand) (Condition, error) {
+	return &ratioCondition{
+		Numerator:   d.Attributes[0],
+		Denominator: d.Attributes[1],
+		Indices:     [2]int{0, 1},
+		Threshold:   r.Float64() * 2,
+	}, nil
+}
+
+func init() {
+	RegisterCondition("ratio", &ratioCondition{})
+}
+
+// positiveDataSet returns rows where Y is close to X, both positive.
+func positiveDataSet() *DataSet {
+	x := Attribute{Name: "X", Type: AttributeTypeNumerical}
+	y := Attribute{Name: "Y", Type: AttributeTypeNumerical}
+	ds := NewDataSet()
+	ds.AddAttribute(x)
+	ds.AddAttribute(y)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		v := math.Exp(r.NormFloat64() * 2)
+		ds.AddRow(map[Attribute]AttributeValue{x: {Num: v}, y: {Num: v * (1 + r.NormFloat64()*0.05)}})
+	}
+	return ds
+}
+
+func TestCustomSplitStrategy(t *testing.T) {
+	ds := positiveDataSet()
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 50, SampleSize: 256, Seed: 3, SplitStrategy: logThresholdSplit{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tree := range forest.Trees {
+		tree.Root.walk(0, func(node *IsolationTreeNode, _ int) {
+			if split, ok := node.split.(*splitCondition); ok && split.numVal <= 0 {
+				t.Errorf("Expected positive thresholds, got %v", split.String(false))
+			}
+		})
+	}
+
+	inlier := forest.Score(map[string]string{"X": "1", "Y": "1"}).Score
+	if outlier := forest.Score(map[string]string{"X": "10000", "Y": "10000"}).Score; outlier <= inlier {
+		t.Errorf("Expected outlier score %f to exceed inlier score %f", outlier, inlier)
+	}
+}
+
+var errSplitFailed = errors.New("split failed")
+
+// failingSplit splits as RandomSplit, but fails on nodes with at most maxSize
+// rows.
+type failingSplit struct {
+	maxSize int
+}
+
+func (s failingSplit) Split(d *DataSet, candidates []int, r *rand.Rand) (Condition, error) {
+	if d.Size <= s.maxSize {
+		return nil, errSplitFailed
+	}
+	return RandomSplit{}.Split(d, candidates, r)
+}
+
+func TestSplitStrategyError(t *testing.T) {
+	ds := testDataSet(300)
+	for _, maxSize := range []int{256, 64} {
+		t.Run(fmt.Sprintf("maxSize=%d", maxSize), func(t *testing.T) {
+			_, err := BuildForestWithConfig(ds, ForestConfig{
+				NumTrees: 10, SampleSize: 256, Seed: 3, SplitStrategy: failingSplit{maxSize: maxSize},
+			})
+			if !errors.Is(err, errSplitFailed) {
+				t.Errorf("Expected the strategy's error, got %v", err)
+			}
+		})
+	}
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 10, SampleSize: 256, Seed: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	forest.config.SplitStrategy = failingSplit{maxSize: 256}
+	if err := forest.AddTrees(ds, 5, 7); !errors.Is(err, errSplitFailed) {
+		t.Errorf("Expected the strategy's error from AddTrees, got %v", err)
+	}
+	if len(forest.Trees) != 10 {
+		t.Errorf("Expected no trees to be added, got %d trees", len(forest.Trees))
+	}
+}
+
+func TestCustomCondition(t *testing.T) {
+	ds := positiveDataSet()
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 50, SampleSize: 256, Seed: 3, SplitStrategy: ratioSplit{},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	point := map[string]string{"X": "1", "Y": "3"}
+	inlier := forest.Score(map[string]string{"X": "1", "Y": "1"}).Score
+	if outlier := forest.Score(point).Score; outlier <= inlier {
+		t.Errorf("Expected outlier score %f to exceed inlier score %f", outlier, inlier)
+	}
+
+	explanation, err := forest.Explain(point, ScoreOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	contributions := explanation.Ranked
+	if len(contributions) != 2 || math.Abs(contributions[0].Contribution-contributions[1].Contribution) > 1e-9 {
+		t.Errorf("Expected both attributes of the ratio to be credited equally, got %v", contributions)
+	}
+
+	result, err := forest.ScoreWithOptions(point, ScoreOptions{Trace: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	step := result.TreeTraces[0].Steps[0]
+	if len(step.Attributes) != 2 || !strings.HasPrefix(step.String(), "X/Y") {
+		t.Errorf("Expected a step on the ratio of X and Y, got %v", step)
+	}
+
+	// Custom strategies are not saved, so only the trees can be compared.
+	var buf bytes.Buffer
+	if err := forest.Save(&buf); err != nil {
+		t.Fatalf("Unexpected error saving: %v", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error loading: %v", err)
+	}
+	if !reflect.DeepEqual(forest.Trees, loaded.Trees) || loaded.Config().SplitStrategy != nil {
+		t.Errorf("Expected loaded trees to equal saved trees")
+	}
+}
+
+func TestCategorySets(t *testing.T) {
+	merchant := Attribute{Name: "Merchant", Type: AttributeTypeCategorical}
+	ds := NewDataSet()
+	ds.AddAttribute(merchant)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		ds.AddRow(map[Attribute]AttributeValue{merchant: {Str: fmt.Sprintf("m%d", r.Intn(200))}})
+	}
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 256, Seed: 3, SplitStrategy: RandomSplit{CategorySets: true},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, tree := range forest.Trees {
+		split, ok := tree.Root.split.(*setCondition)
+		if !ok || len(split.left) == 0 || len(split.right) == 0 {
+			t.Fatalf("Expected root split on two non-empty sets, got %v", tree.Root.split)
+		}
+		// With random sets, each branch of the root holds a sizeable share
+		// of the sample rather than a single category.
+		if tree.Root.left.remainingSize < 30 || tree.Root.right.remainingSize < 30 {
+			t.Errorf("Expected balanced root split, got %d and %d",
+				tree.Root.left.remainingSize, tree.Root.right.remainingSize)
+		}
+	}
+
+	result, err := forest.ScoreWithOptions(map[string]string{"Merchant": "unseen"}, ScoreOptions{Trace: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, trace := range result.TreeTraces {
+		for _, step := range trace.Steps {
+			if step.BranchSize*2 > step.Size {
+				t.Fatalf("Expected unseen category to follow the smaller branch, got %v of %v", step.BranchSize, step.Size)
+			}
+		}
+	}
+	if seen := forest.Score(map[string]string{"Merchant": "m1"}).Score; result.Score <= seen {
+		t.Errorf("Expected unseen category score %f to exceed seen category score %f", result.Score, seen)
+	}
+}
+
+func TestCategorySetUnseen(t *testing.T) {
+	color := Attribute{Name: "Color", Type: AttributeTypeCategorical}
+	left := newSetCondition(color, 0, []string{"red", "blue"}, []string{"green"}, false)
+
+	for value, expected := range map[string]bool{"red": true, "blue": true, "green": false, "purple": false} {
+		if matches := left.Matches(attributeValues{{Str: value}}); matches != expected {
+			t.Errorf("Expected %s to match %v, got %v", value, expected, matches)
+		}
+	}
+	if !left.Missing(attributeValues{{Missing: true}}) {
+		t.Errorf("Expected missing value to be reported")
+	}
+	if s := left.String(true); s != "Color not in {blue, red}" {
+		t.Errorf("Unexpected description %q", s)
+	}
+}
+
+func TestHyperplaneSplitCandidates(t *testing.T) {
+	ds, err := testDataSet(100).With([]string{"X", "Y"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	splitter := hyperplaneSplitter{extensionLevel: 1}
+	r := rand.New(rand.NewSource(7))
+
+	for i := 0; i < 20; i++ {
+		c, err := splitter.Split(ds, []int{1}, r)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if attrs := c.Attributes(); len(attrs) != 1 || attrs[0].Name != "Y" {
+			t.Fatalf("Expected a split on the candidate Y only, got %v", attrs)
+		}
+	}
+	if _, err := splitter.Split(ds, nil, r); !errors.Is(err, ErrNotSplittable) {
+		t.Errorf("Expected ErrNotSplittable without candidates, got %v", err)
+	}
+}
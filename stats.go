@@ -2,6 +2,7 @@ package goiforest
 
 import (
 	"math"
+	"sort"
 )
 
 func min(values []float64) float64 {
@@ -71,3 +72,20 @@ func variance(values []float64) float64 {
 
 	return variance
 }
+
+// quantile returns the q-quantile of values, interpolating linearly between
+// the closest ranks.
+func quantile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (pos-float64(lower))*(sorted[upper]-sorted[lower])
+}
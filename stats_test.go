@@ -2,6 +2,7 @@ package goiforest
 
 import (
 	"fmt"
+	"math"
 	"testing"
 )
 
@@ -28,3 +29,26 @@ func TestKurtosis(t *testing.T) {
 		})
 	}
 }
+
+func TestQuantile(t *testing.T) {
+	cases := []struct {
+		values   []float64
+		q        float64
+		expected float64
+	}{
+		{[]float64{3, 1, 2}, 0, 1},
+		{[]float64{3, 1, 2}, 1, 3},
+		{[]float64{3, 1, 2}, 0.5, 2},
+		{[]float64{1, 2, 3, 4}, 0.5, 2.5},
+		{[]float64{1, 2, 3, 4, 5}, 0.9, 4.6},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v@%v", c.values, c.q), func(t *testing.T) {
+			actual := quantile(c.values, c.q)
+			if math.Abs(actual-c.expected) > 1e-9 {
+				t.Errorf("Expected %f, but got %f", c.expected, actual)
+			}
+		})
+	}
+}
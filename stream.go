@@ -0,0 +1,187 @@
+package goiforest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// ErrNotReady is returned when scoring with a streaming forest that has not
+// yet seen enough points to build its first forest.
+var ErrNotReady = errors.New("streaming forest has not seen enough points")
+
+// StreamingConfig controls NewStreamingForest.
+type StreamingConfig struct {
+	// Forest configures each forest built from the window. Its SampleSize
+	// must not exceed WindowSize. Its Seed seeds the sequence of rebuilds; if
+	// zero, a random seed is chosen and recorded in Config.
+	Forest ForestConfig
+	// WindowSize is the number of most recent points kept for training.
+	WindowSize int
+	// RebuildInterval is the number of points inserted between rebuilds of
+	// the forest from the window.
+	RebuildInterval int
+	// MissingTokens lists values that are treated as missing rather than
+	// parsed, as in ScoreOptions.
+	MissingTokens []string
+}
+
+// DefaultStreamingConfig returns a configuration keeping the last 2048 points
+// and rebuilding after every 256.
+func DefaultStreamingConfig() StreamingConfig {
+	return StreamingConfig{
+		Forest:          DefaultForestConfig(),
+		WindowSize:      2048,
+		RebuildInterval: 256,
+	}
+}
+
+func (c StreamingConfig) validate() error {
+	if c.Forest.SampleSize < 2 {
+		return fmt.Errorf("sample size must be at least 2, got %d", c.Forest.SampleSize)
+	}
+	if c.WindowSize < c.Forest.SampleSize {
+		return fmt.Errorf("window size %d smaller than sample size %d", c.WindowSize, c.Forest.SampleSize)
+	}
+	if c.RebuildInterval <= 0 {
+		return fmt.Errorf("rebuild interval must be positive, got %d", c.RebuildInterval)
+	}
+	return nil
+}
+
+// StreamingForest scores and learns from an unbounded stream of points. It
+// keeps a sliding window of the most recent points and periodically rebuilds
+// its forest from them, so old data ages out and memory stays bounded by the
+// window size. A StreamingForest is not safe for concurrent use.
+type StreamingForest struct {
+	config     StreamingConfig
+	window     *DataSet
+	next       int
+	sinceBuild int
+	forest     *IsolationForest
+	r          *rand.Rand
+}
+
+// NewStreamingForest returns a streaming forest over points with the given
+// attributes.
+func NewStreamingForest(attributes []Attribute, config StreamingConfig) (*StreamingForest, error) {
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid streaming config: %w", err)
+	}
+	if len(attributes) == 0 {
+		return nil, fmt.Errorf("invalid streaming config: no attributes")
+	}
+
+	for config.Forest.Seed == 0 {
+		config.Forest.Seed = rand.Int63()
+	}
+
+	window := NewDataSet()
+	for _, attr := range attributes {
+		window.AddAttribute(attr)
+	}
+
+	return &StreamingForest{
+		config: config,
+		window: window,
+		r:      rand.New(rand.NewSource(config.Forest.Seed)),
+	}, nil
+}
+
+// Config returns the configuration of the streaming forest.
+func (s *StreamingForest) Config() StreamingConfig {
+	return s.config
+}
+
+// Ready reports whether the streaming forest has built a forest and can score
+// points.
+func (s *StreamingForest) Ready() bool {
+	return s.forest != nil
+}
+
+// Forest returns the forest currently used for scoring, or nil if none has
+// been built yet. The forest is replaced, not modified, by later rebuilds.
+func (s *StreamingForest) Forest() *IsolationForest {
+	return s.forest
+}
+
+// Score scores a data point against the current forest without adding it to
+// the window. It returns ErrNotReady if no forest has been built yet.
+func (s *StreamingForest) Score(dataPoint map[string]string) (ScoreResult, error) {
+	if s.forest == nil {
+		return ScoreResult{}, ErrNotReady
+	}
+	return s.forest.ScoreWithOptions(dataPoint, ScoreOptions{MissingTokens: s.config.MissingTokens})
+}
+
+// Insert adds a data point to the window, replacing the oldest point once the
+// window is full. The forest is first built once the window holds a sample's
+// worth of points, and rebuilt after every RebuildInterval insertions.
+func (s *StreamingForest) Insert(dataPoint map[string]string) error {
+	row := make(map[Attribute]AttributeValue, len(s.window.Attributes))
+	for _, attr := range s.window.Attributes {
+		val, exists := dataPoint[attr.Name]
+		if !exists {
+			return &MissingAttributeError{Attribute: attr.Name}
+		}
+		if isMissingToken(val, s.config.MissingTokens) {
+			row[attr] = AttributeValue{Missing: true}
+			continue
+		}
+		attrVal, err := ParseAttributeValue(attr, val)
+		if err != nil {
+			return err
+		}
+		row[attr] = attrVal
+	}
+
+	if s.window.Size < s.config.WindowSize {
+		s.window.AddRow(row)
+	} else {
+		for attr, val := range row {
+			s.window.SetValue(s.next, attr, val)
+		}
+		s.next = (s.next + 1) % s.config.WindowSize
+	}
+
+	s.sinceBuild++
+	if s.forest == nil && s.window.Size >= s.config.Forest.SampleSize ||
+		s.forest != nil && s.sinceBuild >= s.config.RebuildInterval {
+		return s.rebuild()
+	}
+	return nil
+}
+
+// Update scores a data point against the current forest and then inserts it.
+// If no forest has been built yet, the point is inserted and ErrNotReady is
+// returned. Likewise, if the forest's policy is UnseenError and the point has
+// a category the forest has not seen, the point is inserted, so the category
+// is seen once the forest is rebuilt, and the *UnseenCategoryError is
+// returned.
+func (s *StreamingForest) Update(dataPoint map[string]string) (ScoreResult, error) {
+	result, scoreErr := s.Score(dataPoint)
+	var unseenErr *UnseenCategoryError
+	if scoreErr != nil && !errors.Is(scoreErr, ErrNotReady) && !errors.As(scoreErr, &unseenErr) {
+		return ScoreResult{}, scoreErr
+	}
+	if err := s.Insert(dataPoint); err != nil {
+		return ScoreResult{}, err
+	}
+	return result, scoreErr
+}
+
+func (s *StreamingForest) rebuild() error {
+	config := s.config.Forest
+	config.Seed = 0
+	for config.Seed == 0 {
+		config.Seed = s.r.Int63()
+	}
+
+	forest, err := BuildForestWithConfig(s.window, config)
+	if err != nil {
+		return fmt.Errorf("error rebuilding streaming forest: %w", err)
+	}
+	s.forest = forest
+	s.sinceBuild = 0
+	return nil
+}
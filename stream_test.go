@@ -0,0 +1,119 @@
+package goiforest
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func streamPoint(r *rand.Rand, center float64) map[string]string {
+	return map[string]string{
+		"X": strconv.FormatFloat(center+r.NormFloat64(), 'f', -1, 64),
+		"Y": strconv.FormatFloat(center+r.NormFloat64(), 'f', -1, 64),
+	}
+}
+
+func TestStreamingForest(t *testing.T) {
+	attributes := []Attribute{
+		{Name: "X", Type: AttributeTypeNumerical},
+		{Name: "Y", Type: AttributeTypeNumerical},
+	}
+	config := StreamingConfig{
+		Forest:          ForestConfig{NumTrees: 50, SampleSize: 64, Seed: 37},
+		WindowSize:      256,
+		RebuildInterval: 64,
+	}
+	stream, err := NewStreamingForest(attributes, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 63; i++ {
+		if _, err := stream.Update(streamPoint(r, 0)); !errors.Is(err, ErrNotReady) {
+			t.Fatalf("Expected ErrNotReady before the first build, got %v", err)
+		}
+	}
+	if _, err := stream.Update(streamPoint(r, 0)); !errors.Is(err, ErrNotReady) || !stream.Ready() {
+		t.Fatalf("Expected the forest to be built on reaching the sample size, got %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if _, err := stream.Update(streamPoint(r, 0)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if stream.window.Size != config.WindowSize {
+		t.Errorf("Expected window size %d, got %d", config.WindowSize, stream.window.Size)
+	}
+
+	shifted := map[string]string{"X": "10", "Y": "10"}
+	before, err := stream.Score(shifted)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if err := stream.Insert(streamPoint(r, 10)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	after, err := stream.Score(shifted)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if before.Score <= 0.6 || after.Score >= 0.5 {
+		t.Errorf("Expected the shifted point to become normal as old data ages out, scored %f then %f",
+			before.Score, after.Score)
+	}
+}
+
+func TestStreamingConfigInvalid(t *testing.T) {
+	attributes := []Attribute{{Name: "X", Type: AttributeTypeNumerical}}
+	for _, config := range []StreamingConfig{
+		{Forest: ForestConfig{NumTrees: 10, SampleSize: 0}, WindowSize: 10, RebuildInterval: 1},
+		{Forest: ForestConfig{NumTrees: 10, SampleSize: 1}, WindowSize: 10, RebuildInterval: 1},
+		{Forest: ForestConfig{NumTrees: 10, SampleSize: 20}, WindowSize: 10, RebuildInterval: 1},
+		{Forest: ForestConfig{NumTrees: 10, SampleSize: 10}, WindowSize: 10, RebuildInterval: 0},
+	} {
+		if _, err := NewStreamingForest(attributes, config); err == nil {
+			t.Errorf("Expected error for config %+v", config)
+		}
+	}
+}
+
+func TestStreamingForestLearnsUnseenCategories(t *testing.T) {
+	attributes := []Attribute{
+		{Name: "X", Type: AttributeTypeNumerical},
+		{Name: "Color", Type: AttributeTypeCategorical},
+	}
+	config := StreamingConfig{
+		Forest:          ForestConfig{NumTrees: 10, SampleSize: 32, Seed: 41, UnseenCategories: UnseenError},
+		WindowSize:      64,
+		RebuildInterval: 8,
+	}
+	stream, err := NewStreamingForest(attributes, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	point := func(color string) map[string]string {
+		return map[string]string{"X": strconv.FormatFloat(r.NormFloat64(), 'f', -1, 64), "Color": color}
+	}
+	for i := 0; i < 32; i++ {
+		stream.Update(point("red"))
+	}
+
+	var unseenErr *UnseenCategoryError
+	if _, err := stream.Update(point("blue")); !errors.As(err, &unseenErr) {
+		t.Fatalf("Expected *UnseenCategoryError, got %v", err)
+	}
+	for i := 0; i < config.RebuildInterval; i++ {
+		stream.Update(point("red"))
+	}
+	if _, err := stream.Update(point("blue")); err != nil {
+		t.Errorf("Expected the category to be learned after a rebuild, got %v", err)
+	}
+}
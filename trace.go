@@ -0,0 +1,133 @@
+package goiforest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Branch identifies which child of a node a data point followed.
+type Branch int
+
+const (
+	BranchLeft Branch = iota
+	BranchRight
+	// BranchBoth is used when the data point is missing a value needed by
+	// the split, so both children are followed.
+	BranchBoth
+)
+
+func (b Branch) String() string {
+	switch b {
+	case BranchLeft:
+		return "left"
+	case BranchRight:
+		return "right"
+	}
+	return "both"
+}
+
+// TraceStep records a single split visited while scoring a data point.
+// Threshold is set for numerical splits and Category for categorical splits,
+// or Categories, holding the categories that follow the left branch, for
+// category set splits.
+// Steps through hyperplane splits of an extended forest set Attributes and
+// Normal instead of Attribute, with Value holding the projection of the data
+// point onto Normal and Threshold the offset it is compared against. Steps
+// through conditions of other types only set Attributes, and Attribute if
+// there is one. Size is the number of training rows that reached the node and
+// BranchSize the number that followed the same branch as the data point.
+// Weight is the share of the tree's path length passing through the step,
+// which is less than one only below a split that followed both branches.
+type TraceStep struct {
+	NodeID     int
+	Depth      int
+	Size       int
+	BranchSize int
+	Weight     float64
+	Attribute  Attribute
+	Value      AttributeValue
+	Threshold  float64
+	Category   string
+	Categories []string
+	Attributes []Attribute
+	Normal     []float64
+	Branch     Branch
+	split      Condition
+}
+
+// addStep appends the step through node to t. The step is filled in place, so
+// tracing into a reused trace does not allocate once its steps have grown.
+func (t *TreeTrace) addStep(node *IsolationTreeNode, row RowValues, depth float64, weight float64, branch Branch) {
+	t.Steps = append(t.Steps, TraceStep{
+		NodeID:     node.id,
+		Depth:      int(depth),
+		Size:       node.remainingSize,
+		BranchSize: node.remainingSize,
+		Weight:     weight,
+		Branch:     branch,
+		split:      node.split,
+	})
+	step := &t.Steps[len(t.Steps)-1]
+	switch branch {
+	case BranchLeft:
+		step.BranchSize = node.left.remainingSize
+	case BranchRight:
+		step.BranchSize = node.right.remainingSize
+	}
+	if d, ok := node.split.(describer); ok {
+		d.describe(step, row)
+	} else {
+		step.Attributes = node.split.Attributes()
+		if len(step.Attributes) == 1 {
+			step.Attribute = step.Attributes[0]
+		}
+	}
+}
+
+func (s TraceStep) String() string {
+	if s.Branch == BranchBoth {
+		return fmt.Sprintf("%s (missing)", s.split.String(false))
+	}
+	if _, ok := s.split.(describer); !ok {
+		return s.split.String(s.Branch == BranchRight)
+	}
+
+	value := fmt.Sprintf("%f", s.Value.Num)
+	if s.Normal == nil {
+		value = s.Attribute.ValueToString(s.Value)
+	}
+	return fmt.Sprintf("%s (%s)", s.split.String(s.Branch == BranchRight), value)
+}
+
+// TraceLeaf records a leaf reached while scoring a data point. Weight is the
+// share of the path length contributed by the leaf, which is less than one
+// only if a missing value caused more than one leaf to be reached.
+type TraceLeaf struct {
+	ID     int
+	Size   int
+	Weight float64
+}
+
+// TreeTrace records the path of a data point through a single tree. Steps are
+// in depth first order, so when a missing value causes both children of a node
+// to be followed, the steps of the left subtree precede those of the right.
+// PathLength includes the adjustment for the number of training rows
+// remaining at each leaf.
+type TreeTrace struct {
+	Steps      []TraceStep
+	Leaves     []TraceLeaf
+	PathLength float64
+}
+
+func (t TreeTrace) String() string {
+	var sb strings.Builder
+	for _, step := range t.Steps {
+		sb.WriteString(step.String())
+		sb.WriteString("\n")
+	}
+	for _, leaf := range t.Leaves {
+		sb.WriteString(fmt.Sprintf("Hit leaf %d, remaining size: %d, weight: %f\n", leaf.ID, leaf.Size, leaf.Weight))
+	}
+	sb.WriteString(fmt.Sprintf("Path length %f\n", t.PathLength))
+	return sb.String()
+}
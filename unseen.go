@@ -0,0 +1,224 @@
+package goiforest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// UnseenCategoryPolicy controls how a forest scores values of categorical
+// attributes that were not seen in the data set it was built from.
+type UnseenCategoryPolicy int
+
+const (
+	// UnseenFollowSplit routes unseen values as each split's condition does:
+	// away from the category of an equality split, and down the branch fewer
+	// training rows followed at a category set split.
+	UnseenFollowSplit UnseenCategoryPolicy = iota
+	// UnseenAnomalous scores data points with an unseen value 1, the highest
+	// possible score, with an average path length of zero and no traces.
+	UnseenAnomalous
+	// UnseenRandom routes unseen values down a random branch at each split on
+	// their attribute, chosen with the probability a training row followed
+	// it. The choice is seeded by the forest's seed and the unseen values, so
+	// a data point always gets the same score.
+	UnseenRandom
+	// UnseenError fails to score data points with an unseen value, returning
+	// an *UnseenCategoryError.
+	UnseenError
+)
+
+// String returns "follow", "anomalous", "random" or "error".
+func (p UnseenCategoryPolicy) String() string {
+	switch p {
+	case UnseenFollowSplit:
+		return "follow"
+	case UnseenAnomalous:
+		return "anomalous"
+	case UnseenRandom:
+		return "random"
+	case UnseenError:
+		return "error"
+	}
+	return "UnseenCategoryPolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+// ParseUnseenCategoryPolicy parses the name of a policy as returned by
+// UnseenCategoryPolicy.String.
+func ParseUnseenCategoryPolicy(name string) (UnseenCategoryPolicy, error) {
+	for p := UnseenFollowSplit; p <= UnseenError; p++ {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unseen category policy %q", name)
+}
+
+// UnseenCategoryError is returned when scoring a data point with a value of a
+// categorical attribute that was not seen when building a forest whose
+// policy is UnseenError.
+type UnseenCategoryError struct {
+	Attribute string
+	Value     string
+}
+
+func (e *UnseenCategoryError) Error() string {
+	return fmt.Sprintf("value %q of attribute %s not seen in training", e.Value, e.Attribute)
+}
+
+// seenCategories returns the distinct values of each categorical attribute of
+// dataSet by position, with nil for numerical attributes.
+func seenCategories(dataSet *DataSet) []map[string]bool {
+	seen := make([]map[string]bool, len(dataSet.Attributes))
+	for i, attr := range dataSet.Attributes {
+		if attr.Type != AttributeTypeCategorical {
+			continue
+		}
+		categories, _ := dataSet.categoryCounts(i)
+		seen[i] = make(map[string]bool, len(categories))
+		for _, category := range categories {
+			seen[i][category] = true
+		}
+	}
+	return seen
+}
+
+// mergeCategories returns the union of a and b, either of which may be nil
+// if the categories are not known.
+func mergeCategories(a, b []map[string]bool) []map[string]bool {
+	if a == nil || b == nil {
+		return nil
+	}
+	merged := make([]map[string]bool, len(a))
+	for i := range a {
+		if a[i] == nil {
+			continue
+		}
+		merged[i] = make(map[string]bool, len(a[i]))
+		for _, categories := range []map[string]bool{a[i], b[i]} {
+			for category := range categories {
+				merged[i][category] = true
+			}
+		}
+	}
+	return merged
+}
+
+// sortedCategories returns the keys of categories in order.
+func sortedCategories(categories map[string]bool) []string {
+	sorted := make([]string, 0, len(categories))
+	for category := range categories {
+		sorted = append(sorted, category)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// unseenValues appends to unseen the positions of the attributes of row with
+// values not seen in training, and returns it. Nothing is unseen if the forest
+// does not know its training categories, as for models saved before they were
+// recorded.
+func (f *IsolationForest) unseenValues(row RowValues, unseen []int) []int {
+	for i, categories := range f.categories {
+		if categories == nil {
+			continue
+		}
+		if v := row.Value(i); !v.Missing && !categories[v.Str] {
+			unseen = append(unseen, i)
+		}
+	}
+	return unseen
+}
+
+// unseenError returns the error for the first unseen value of row.
+func (f *IsolationForest) unseenError(row RowValues, unseen []int) error {
+	return &UnseenCategoryError{Attribute: f.attributes[unseen[0]].Name, Value: row.Value(unseen[0]).Str}
+}
+
+// traversed reports whether a data point whose values at positions unseen
+// were not seen in training is scored by traversing the trees, rather than
+// scored 1 under the UnseenAnomalous policy.
+func (f *IsolationForest) traversed(unseen []int) bool {
+	return len(unseen) == 0 || f.config.UnseenCategories != UnseenAnomalous
+}
+
+// policyPathLength returns the average path length of row, whose values at
+// positions unseen were not seen in training, applying the forest's unseen
+// category policy. The UnseenError policy must be handled by the caller.
+func (f *IsolationForest) policyPathLength(row RowValues, unseen []int, traces []TreeTrace) float64 {
+	if len(unseen) == 0 {
+		return f.averagePathLength(row, nil, traces)
+	}
+	switch f.config.UnseenCategories {
+	case UnseenAnomalous:
+		return 0
+	case UnseenRandom:
+		return f.averagePathLength(row, f.newUnseenRouter(row, unseen), traces)
+	}
+	return f.averagePathLength(row, nil, traces)
+}
+
+// unseenRouter chooses random branches for unseen values under the
+// UnseenRandom policy. It draws from a splitmix64 sequence, which unlike
+// math/rand needs no allocation to seed.
+type unseenRouter struct {
+	attributes []Attribute
+	indices    []int
+	state      uint64
+}
+
+// newUnseenRouter returns a router for the values of row at positions unseen,
+// seeded by the forest's seed and those values.
+func (f *IsolationForest) newUnseenRouter(row RowValues, unseen []int) *unseenRouter {
+	r := &unseenRouter{indices: unseen, state: uint64(f.config.Seed)}
+	for _, i := range unseen {
+		r.attributes = append(r.attributes, f.attributes[i])
+		// FNV-1a over the value, folded into the seed.
+		h := uint64(14695981039346656037)
+		for _, b := range []byte(row.Value(i).Str) {
+			h ^= uint64(b)
+			h *= 1099511628211
+		}
+		r.state ^= h + uint64(i)
+	}
+	return r
+}
+
+// routes reports whether split depends on an unseen value.
+func (r *unseenRouter) routes(split Condition) bool {
+	var index int
+	switch c := split.(type) {
+	case *splitCondition:
+		index = c.index
+	case *setCondition:
+		index = c.index
+	default:
+		for _, attr := range split.Attributes() {
+			for _, unseen := range r.attributes {
+				if attr == unseen {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, i := range r.indices {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+// left reports whether to follow the left branch of n, with the probability a
+// training row did.
+func (r *unseenRouter) left(n *IsolationTreeNode) bool {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	p := float64(z>>11) / (1 << 53)
+	return p < float64(n.left.remainingSize)/float64(n.remainingSize)
+}
@@ -0,0 +1,138 @@
+package goiforest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnseenCategoryPolicies(t *testing.T) {
+	ds := testDataSet(300)
+	color := Attribute{Name: "Color", Type: AttributeTypeCategorical}
+	seen := map[string]string{"X": "0.5", "Y": "0.5", "Color": "red"}
+	unseen := map[string]string{"X": "0.5", "Y": "0.5", "Color": "purple"}
+
+	forests := map[UnseenCategoryPolicy]*IsolationForest{}
+	for _, policy := range []UnseenCategoryPolicy{UnseenFollowSplit, UnseenAnomalous, UnseenRandom, UnseenError} {
+		forest, err := BuildForestWithConfig(ds, ForestConfig{
+			NumTrees: 50, SampleSize: 128, Seed: 3, UnseenCategories: policy,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		forests[policy] = forest
+
+		result, err := forest.TryScore(seen)
+		if err != nil || len(result.Unseen) != 0 {
+			t.Fatalf("%v: expected seen value to score without unseen attributes, got %v, %v", policy, result.Unseen, err)
+		}
+		if result.Score != forests[UnseenFollowSplit].Score(seen).Score {
+			t.Errorf("%v: expected the policy not to affect seen values", policy)
+		}
+		missing, err := forest.ScoreWithOptions(map[string]string{"X": "0", "Y": "0", "Color": "NA"},
+			ScoreOptions{MissingTokens: []string{"NA"}})
+		if err != nil || len(missing.Unseen) != 0 {
+			t.Errorf("%v: expected missing value not to be unseen, got %v, %v", policy, missing.Unseen, err)
+		}
+	}
+
+	follow := forests[UnseenFollowSplit].Score(unseen)
+	if !reflect.DeepEqual(follow.Unseen, []Attribute{color}) {
+		t.Errorf("Expected Color to be unseen, got %v", follow.Unseen)
+	}
+	if other := forests[UnseenFollowSplit].Score(map[string]string{"X": "0.5", "Y": "0.5", "Color": "orange"}); other.Score != follow.Score {
+		t.Errorf("Expected unseen values to follow the same splits, got %f and %f", other.Score, follow.Score)
+	}
+
+	anomalous := forests[UnseenAnomalous].Score(unseen)
+	if anomalous.Score != 1 || anomalous.AveragePathLength != 0 || len(anomalous.Unseen) != 1 {
+		t.Errorf("Expected unseen value to score 1, got %+v", anomalous)
+	}
+	explanation, err := forests[UnseenAnomalous].Explain(unseen, ScoreOptions{})
+	if err != nil || explanation.Contributions[color] != 1 {
+		t.Errorf("Expected Color to get all the credit, got %v, %v", explanation.Contributions, err)
+	}
+
+	random := forests[UnseenRandom].Score(unseen)
+	if again := forests[UnseenRandom].Score(unseen); again.Score != random.Score {
+		t.Errorf("Expected random routing to be repeatable, got %f and %f", random.Score, again.Score)
+	}
+	if random.Score == follow.Score {
+		t.Errorf("Expected random routing to differ from following splits")
+	}
+
+	var unseenErr *UnseenCategoryError
+	if _, err := forests[UnseenError].TryScore(unseen); !errors.As(err, &unseenErr) || unseenErr.Value != "purple" {
+		t.Errorf("Expected *UnseenCategoryError, got %v", err)
+	}
+	row := forests[UnseenError].NewRow()
+	row.SetStr(2, "purple")
+	if _, err := forests[UnseenError].ScoreVector(row); !errors.As(err, &unseenErr) {
+		t.Errorf("Expected *UnseenCategoryError from ScoreVector, got %v", err)
+	}
+}
+
+func TestUnseenCategoriesDataSet(t *testing.T) {
+	ds := testDataSet(300)
+	color := Attribute{Name: "Color", Type: AttributeTypeCategorical}
+	scored := ds.Copy()
+	scored.SetValue(7, color, AttributeValue{Str: "purple"})
+
+	forest, err := BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 128, Seed: 3, UnseenCategories: UnseenError,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var unseenErr *UnseenCategoryError
+	if _, err := forest.ScoreDataSet(scored, BatchOptions{}); !errors.As(err, &unseenErr) {
+		t.Errorf("Expected *UnseenCategoryError, got %v", err)
+	}
+
+	forest, err = BuildForestWithConfig(ds, ForestConfig{
+		NumTrees: 20, SampleSize: 128, Seed: 3, UnseenCategories: UnseenAnomalous,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, err := forest.ScoreDataSet(scored, BatchOptions{Traces: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Scores[7] != 1 || result.Scores[6] == 1 {
+		t.Errorf("Expected only row 7 to score 1, got %f and %f", result.Scores[7], result.Scores[6])
+	}
+	single, err := forest.ScoreWithOptions(scored.GetRowPlain(7), ScoreOptions{Trace: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.TreeTraces[7] != nil || single.TreeTraces != nil {
+		t.Errorf("Expected no traces for row 7, got %v and %v", result.TreeTraces[7], single.TreeTraces)
+	}
+	if len(result.TreeTraces[6]) != len(forest.Trees) {
+		t.Errorf("Expected traces for row 6, got %d", len(result.TreeTraces[6]))
+	}
+
+	if err := forest.AddTrees(scored, 5, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if purple := forest.Score(scored.GetRowPlain(7)); len(purple.Unseen) != 0 {
+		t.Errorf("Expected categories of added trees to be seen, got %v", purple.Unseen)
+	}
+}
+
+func TestParseUnseenCategoryPolicy(t *testing.T) {
+	for p := UnseenFollowSplit; p <= UnseenError; p++ {
+		if parsed, err := ParseUnseenCategoryPolicy(p.String()); err != nil || parsed != p {
+			t.Errorf("Expected %v to parse, got %v, %v", p, parsed, err)
+		}
+	}
+	if _, err := ParseUnseenCategoryPolicy("ignore"); err == nil {
+		t.Errorf("Expected error parsing unknown policy")
+	}
+	if _, err := BuildForestWithConfig(testDataSet(100), ForestConfig{
+		NumTrees: 10, SampleSize: 50, UnseenCategories: UnseenError + 1,
+	}); err == nil {
+		t.Errorf("Expected error for unknown policy")
+	}
+}
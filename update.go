@@ -0,0 +1,174 @@
+package goiforest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+)
+
+// The methods in this file modify the forest in place and must not be called
+// while it is being used by other goroutines. Trees are kept oldest first.
+
+// AddTrees builds n trees from dataSet using the forest's configuration and
+// appends them to the forest. dataSet must contain the forest's attributes
+// with the same types and at least the forest's sample size of rows. seed
+// seeds the new trees; if zero, a random seed is used. If the forest was built
+// with a contamination, its threshold is refitted on dataSet. Categories in
+// dataSet are no longer treated as unseen.
+func (f *IsolationForest) AddTrees(dataSet *DataSet, n int, seed int64) error {
+	if n <= 0 {
+		return fmt.Errorf("number of trees must be positive, got %d", n)
+	}
+	if dataSet.Size < f.config.SampleSize {
+		return fmt.Errorf("sample size %d larger than data set size %d", f.config.SampleSize, dataSet.Size)
+	}
+
+	names := make([]string, len(f.attributes))
+	for i, attr := range f.attributes {
+		names[i] = attr.Name
+	}
+	subset, err := dataSet.With(names)
+	if err != nil {
+		return err
+	}
+	if !sameAttributes(subset.Attributes, f.attributes) {
+		return fmt.Errorf("data set attribute types do not match forest")
+	}
+
+	for seed == 0 {
+		seed = rand.Int63()
+	}
+
+	trees := make([]*IsolationTree, n)
+	if err := buildTrees(subset, trees, f.config.SampleSize, uint(f.config.MaxDepth), f.config.splitter(),
+		f.config.Workers, rand.New(rand.NewSource(seed))); err != nil {
+		return err
+	}
+	f.Trees = append(f.Trees, trees...)
+	f.config.NumTrees = len(f.Trees)
+	f.categories = mergeCategories(f.categories, seenCategories(subset))
+
+	if f.config.Contamination > 0 {
+		return f.fitThreshold(subset)
+	}
+	return nil
+}
+
+// RetireOldest removes the n oldest trees from the forest. At least one tree
+// must remain. The threshold is left unchanged.
+func (f *IsolationForest) RetireOldest(n int) error {
+	if err := f.checkRetire(n); err != nil {
+		return err
+	}
+
+	f.Trees = append([]*IsolationTree(nil), f.Trees[n:]...)
+	f.config.NumTrees = len(f.Trees)
+	return nil
+}
+
+// RetireWorst removes the n trees that fit reference worst, which are those
+// giving its rows the shortest mean path length: trees built on stale data
+// tend to isolate current, normal data quickly. At least one tree must remain.
+// The threshold is left unchanged.
+func (f *IsolationForest) RetireWorst(n int, reference *DataSet) error {
+	if err := f.checkRetire(n); err != nil {
+		return err
+	}
+
+	fits, err := f.treeFits(reference)
+	if err != nil {
+		return err
+	}
+
+	worst := make([]int, len(f.Trees))
+	for i := range worst {
+		worst[i] = i
+	}
+	sort.SliceStable(worst, func(i, j int) bool {
+		return fits[worst[i]] < fits[worst[j]]
+	})
+
+	retired := make(map[int]bool, n)
+	for _, i := range worst[:n] {
+		retired[i] = true
+	}
+	trees := make([]*IsolationTree, 0, len(f.Trees)-n)
+	for i, tree := range f.Trees {
+		if !retired[i] {
+			trees = append(trees, tree)
+		}
+	}
+	f.Trees = trees
+	f.config.NumTrees = len(f.Trees)
+	return nil
+}
+
+func (f *IsolationForest) checkRetire(n int) error {
+	if n <= 0 || n >= len(f.Trees) {
+		return fmt.Errorf("number of trees to retire must be between 1 and %d, got %d", len(f.Trees)-1, n)
+	}
+	return nil
+}
+
+// treeFits returns the mean path length of the rows of reference through each
+// tree.
+func (f *IsolationForest) treeFits(reference *DataSet) ([]float64, error) {
+	if reference.Size == 0 {
+		return nil, fmt.Errorf("reference data set is empty")
+	}
+
+	columns, err := f.columns(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	fits := make([]float64, len(f.Trees))
+	row := make(attributeValues, len(f.attributes))
+	for i := 0; i < reference.Size; i++ {
+		for j, col := range columns {
+			row[j] = col.value(reference.storeRow(i))
+		}
+		for t, tree := range f.Trees {
+			fits[t] += tree.traverse(row, nil, nil)
+		}
+	}
+	for t := range fits {
+		fits[t] /= float64(reference.Size)
+	}
+	return fits, nil
+}
+
+// Merge returns a forest holding the trees of f followed by those of other.
+// Both forests must have the same attributes, sample size, extension, split
+// strategy and unseen category policy. The merged forest takes its
+// configuration and threshold from f, treats categories seen by either forest
+// as seen, and shares its trees with f and other.
+func (f *IsolationForest) Merge(other *IsolationForest) (*IsolationForest, error) {
+	if !sameAttributes(f.attributes, other.attributes) {
+		return nil, fmt.Errorf("forest attributes do not match")
+	}
+	if f.config.SampleSize != other.config.SampleSize {
+		return nil, fmt.Errorf("forest sample sizes %d and %d do not match",
+			f.config.SampleSize, other.config.SampleSize)
+	}
+	if f.config.Extended != other.config.Extended || f.config.ExtensionLevel != other.config.ExtensionLevel {
+		return nil, fmt.Errorf("forest extensions do not match")
+	}
+	if !reflect.DeepEqual(f.config.splitter(), other.config.splitter()) {
+		return nil, fmt.Errorf("forest split strategies do not match")
+	}
+	if f.config.UnseenCategories != other.config.UnseenCategories {
+		return nil, fmt.Errorf("forest unseen category policies %s and %s do not match",
+			f.config.UnseenCategories, other.config.UnseenCategories)
+	}
+
+	merged := *f
+	merged.Trees = make([]*IsolationTree, 0, len(f.Trees)+len(other.Trees))
+	merged.Trees = append(merged.Trees, f.Trees...)
+	merged.Trees = append(merged.Trees, other.Trees...)
+	merged.attributes = append([]Attribute(nil), f.attributes...)
+	merged.categories = mergeCategories(f.categories, other.categories)
+	merged.config.NumTrees = len(merged.Trees)
+	return &merged, nil
+}
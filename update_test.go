@@ -0,0 +1,157 @@
+package goiforest
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func shiftedDataSet(size int, shift float64) *DataSet {
+	ds := testDataSet(size)
+	x := Attribute{Name: "X", Type: AttributeTypeNumerical}
+	for i := 0; i < ds.Size; i++ {
+		ds.SetValue(i, x, AttributeValue{Num: ds.Value(i, x).Num + shift})
+	}
+	return ds
+}
+
+func TestAddAndRetireTrees(t *testing.T) {
+	forest, err := BuildForestWithConfig(testDataSet(500), ForestConfig{NumTrees: 30, SampleSize: 128, Seed: 41})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	old := append([]*IsolationTree(nil), forest.Trees...)
+
+	shifted := shiftedDataSet(500, 20)
+	if err := forest.AddTrees(shifted, 20, 43); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(forest.Trees) != 50 || forest.Config().NumTrees != 50 {
+		t.Fatalf("Expected 50 trees, got %d", len(forest.Trees))
+	}
+	added := append([]*IsolationTree(nil), forest.Trees[30:]...)
+
+	if err := forest.RetireWorst(10, shifted); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(forest.Trees) != 40 {
+		t.Fatalf("Expected 40 trees, got %d", len(forest.Trees))
+	}
+	for i, tree := range added {
+		if forest.Trees[20+i] != tree {
+			t.Errorf("Expected tree %d built on the shifted data to be kept", i)
+		}
+	}
+
+	forest.Trees = append(old, added...)
+	if err := forest.RetireOldest(30); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(forest.Trees) != 20 || forest.Trees[0] != added[0] {
+		t.Errorf("Expected the oldest trees to be retired, got %d trees", len(forest.Trees))
+	}
+
+	if err := forest.RetireOldest(20); err == nil {
+		t.Errorf("Expected error retiring every tree")
+	}
+	if err := forest.AddTrees(shifted.Limit(10), 5, 0); err == nil {
+		t.Errorf("Expected error adding trees from a data set smaller than the sample size")
+	}
+	excluded, _ := shifted.Excluding("Color")
+	if err := forest.AddTrees(excluded, 5, 0); err == nil {
+		t.Errorf("Expected error adding trees from a data set missing an attribute")
+	}
+}
+
+func TestMergeForests(t *testing.T) {
+	ds := testDataSet(500)
+	a, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 20, SampleSize: 128, Seed: 47})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 30, SampleSize: 128, Seed: 53})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	merged, err := a.Merge(b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(merged.Trees) != 50 || len(a.Trees) != 20 {
+		t.Fatalf("Expected 50 merged trees leaving the original unchanged, got %d and %d",
+			len(merged.Trees), len(a.Trees))
+	}
+
+	point := ds.GetRowPlain(0)
+	expected := (20*a.Score(point).AveragePathLength + 30*b.Score(point).AveragePathLength) / 50
+	if actual := merged.Score(point).AveragePathLength; math.Abs(actual-expected) > 1e-9 {
+		t.Errorf("Expected average path length %f, got %f", expected, actual)
+	}
+
+	other, err := BuildForestWithConfig(ds, ForestConfig{NumTrees: 10, SampleSize: 64, Seed: 59})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := a.Merge(other); err == nil {
+		t.Errorf("Expected error merging forests with different sample sizes")
+	}
+}
+
+func TestMergeMismatchedConfigs(t *testing.T) {
+	ds := testDataSet(300)
+	build := func(config ForestConfig) *IsolationForest {
+		config.NumTrees, config.SampleSize, config.Attributes = 10, 64, []string{"X", "Y"}
+		forest, err := BuildForestWithConfig(ds, config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return forest
+	}
+
+	cases := map[string][2]ForestConfig{
+		"extended":       {{}, {Extended: true}},
+		"extension":      {{Extended: true}, {Extended: true, ExtensionLevel: 1}},
+		"split strategy": {{}, {SplitStrategy: SCiForestSplit{}}},
+		"category sets":  {{}, {SplitStrategy: RandomSplit{CategorySets: true}}},
+		"unseen policy":  {{}, {UnseenCategories: UnseenAnomalous}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := build(c[0]).Merge(build(c[1])); err == nil {
+				t.Errorf("Expected error merging forests with different configs")
+			}
+		})
+	}
+
+	if _, err := build(ForestConfig{}).Merge(build(ForestConfig{SplitStrategy: RandomSplit{}})); err != nil {
+		t.Errorf("Unexpected error merging forests using the default split strategy: %v", err)
+	}
+}
+
+func TestAddTreesToLoadedForest(t *testing.T) {
+	forest, err := BuildForestWithConfig(testDataSet(300), ForestConfig{NumTrees: 10, SampleSize: 64, Seed: 47, Workers: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := forest.SaveJSON(&buf); err != nil {
+		t.Fatalf("Unexpected error saving: %v", err)
+	}
+	if strings.Contains(buf.String(), `"workers"`) {
+		t.Errorf("Expected the worker count not to be saved")
+	}
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error loading: %v", err)
+	}
+	if err := loaded.AddTrees(testDataSet(300), 5, 53); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(loaded.Trees) != 15 {
+		t.Errorf("Expected 15 trees, got %d", len(loaded.Trees))
+	}
+}